@@ -0,0 +1,100 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type APIKeyHandler struct {
+	service *APIKeyService
+
+	validate *validator.Validate
+}
+
+func NewAPIKeyHandler(service *APIKeyService, validate *validator.Validate) *APIKeyHandler {
+	return &APIKeyHandler{service: service, validate: validate}
+}
+
+// APIKeyDto creates a key with the given scopes. Keys without ExpiresInDays
+// never expire.
+type APIKeyDto struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=read:bookings write:confirm"`
+	ExpiresInDays int      `json:"expiresInDays" validate:"omitempty,min=1,max=365"`
+}
+
+func (handler *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	var apiKey APIKeyDto
+
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err := c.Bind(&apiKey); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(apiKey); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	createdAPIKey, err := handler.service.CreateAPIKey(userId, apiKey)
+	if err != nil {
+		zap.L().Error("error creating api key", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create api key")
+	}
+
+	return c.JSON(http.StatusCreated, createdAPIKey)
+}
+
+func (handler *APIKeyHandler) GetAPIKeys(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	apiKeys, err := handler.service.GetAPIKeys(userId)
+	if err != nil {
+		zap.L().Error("error getting api keys", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch api keys")
+	}
+
+	return c.JSON(http.StatusOK, apiKeys)
+}
+
+func (handler *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	apiKeyIdStr := c.Param("apiKeyId")
+	if apiKeyIdStr == "" {
+		zap.L().Error("missed apiKeyId")
+		return echo.NewHTTPError(http.StatusBadRequest, "api key ID is required")
+	}
+
+	err := handler.service.RevokeAPIKey(userId, apiKeyIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "api key not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to revoke api key", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error revoking api key", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke api key")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the api key has been revoked"})
+}
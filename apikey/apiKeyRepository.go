@@ -0,0 +1,67 @@
+package apikey
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey is a personal key a user automates their bookings with. Only the
+// SHA-256 of the key is stored; Prefix is its public part, shown to tell keys
+// apart and used to find the key. Scopes is a comma separated list.
+type APIKey struct {
+	ID         uint
+	UserID     uint
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     string
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (apiKeyRepo *APIKeyRepository) AddAPIKey(apiKey *APIKey) error {
+	return apiKeyRepo.db.Create(&apiKey).Error
+}
+
+func (apiKeyRepo *APIKeyRepository) UpdateAPIKey(apiKey *APIKey) error {
+	return apiKeyRepo.db.Save(&apiKey).Error
+}
+
+func (apiKeyRepo *APIKeyRepository) GetAPIKeyById(apiKeyId uint) (*APIKey, error) {
+	var apiKey APIKey
+	err := apiKeyRepo.db.First(&apiKey, apiKeyId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (apiKeyRepo *APIKeyRepository) GetAPIKeyByPrefix(prefix string) (*APIKey, error) {
+	var apiKey APIKey
+	err := apiKeyRepo.db.Where("prefix = ?", prefix).First(&apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (apiKeyRepo *APIKeyRepository) GetUserAPIKeys(userId uint) ([]APIKey, error) {
+	var apiKeys []APIKey
+	err := apiKeyRepo.db.Model(&APIKey{}).Where("user_id = ?", userId).Order("id").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+func (apiKeyRepo *APIKeyRepository) UpdateLastUsed(apiKeyId uint, lastUsedAt time.Time) error {
+	return apiKeyRepo.db.Model(&APIKey{}).Where("id = ?", apiKeyId).Update("last_used_at", lastUsedAt).Error
+}
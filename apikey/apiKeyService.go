@@ -0,0 +1,186 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"rental_service/auth"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// keyPrefix starts every key, so the auth layer can tell keys from JWTs.
+const keyPrefix = "rk_"
+
+// lastUsedPrecision limits the writes of busy keys to one per minute.
+const lastUsedPrecision = time.Minute
+
+var ErrRecordNotFound = errors.New("api key not found")
+var ErrNotAllowed = errors.New("api key belongs to another user")
+var ErrInvalidKey = errors.New("invalid api key")
+
+type APIKeyService struct {
+	repo *APIKeyRepository
+}
+
+func NewAPIKeyService(repo *APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKey issues a new key. The key is only returned here, so users
+// have to keep it.
+func (service *APIKeyService) CreateAPIKey(userId uint, apiKeyDto APIKeyDto) (*APIKeyResponse, error) {
+	prefixBytes := make([]byte, 6)
+	_, err := rand.Read(prefixBytes)
+	if err != nil {
+		return nil, err
+	}
+	secretBytes := make([]byte, 32)
+	_, err = rand.Read(secretBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := keyPrefix + hex.EncodeToString(prefixBytes)
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	apiKey := &APIKey{
+		UserID:    userId,
+		Name:      apiKeyDto.Name,
+		Prefix:    prefix,
+		KeyHash:   hashKey(key),
+		Scopes:    strings.Join(apiKeyDto.Scopes, ","),
+		CreatedAt: time.Now(),
+	}
+	if apiKeyDto.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, apiKeyDto.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	err = service.repo.AddAPIKey(apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyResponse := newAPIKeyResponse(apiKey)
+	apiKeyResponse.Key = key
+	return apiKeyResponse, nil
+}
+
+func (service *APIKeyService) GetAPIKeys(userId uint) ([]APIKeyResponse, error) {
+	apiKeys, err := service.repo.GetUserAPIKeys(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyResponseList := []APIKeyResponse{}
+	for i := range apiKeys {
+		apiKeyResponseList = append(apiKeyResponseList, *newAPIKeyResponse(&apiKeys[i]))
+	}
+	return apiKeyResponseList, nil
+}
+
+// RevokeAPIKey stops the key from working right away. Revoked keys stay
+// listed.
+func (service *APIKeyService) RevokeAPIKey(userId uint, apiKeyIdStr string) error {
+	apiKeyId, err := strconv.ParseUint(apiKeyIdStr, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := service.repo.GetAPIKeyById(uint(apiKeyId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	if apiKey.UserID != userId {
+		return ErrNotAllowed
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	return service.repo.UpdateAPIKey(apiKey)
+}
+
+// VerifyAPIKey implements auth.APIKeyVerifier.
+func (service *APIKeyService) VerifyAPIKey(key string) (*auth.Principal, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+	prefix, _, found := strings.Cut(strings.TrimPrefix(key, keyPrefix), "_")
+	if !found {
+		return nil, ErrInvalidKey
+	}
+
+	apiKey, err := service.repo.GetAPIKeyByPrefix(keyPrefix + prefix)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		return nil, ErrInvalidKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedPrecision {
+		err = service.repo.UpdateLastUsed(apiKey.ID, now)
+		if err != nil {
+			zap.L().Error("error updating api key last use", zap.Uint("apiKeyId", apiKey.ID), zap.Error(err))
+		}
+	}
+
+	return &auth.Principal{
+		UserID:   apiKey.UserID,
+		APIKeyID: apiKey.ID,
+		Scopes:   strings.Split(apiKey.Scopes, ","),
+	}, nil
+}
+
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func newAPIKeyResponse(apiKey *APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     strings.Split(apiKey.Scopes, ","),
+		ExpiresAt:  apiKey.ExpiresAt,
+		RevokedAt:  apiKey.RevokedAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
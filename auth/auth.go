@@ -1,15 +1,44 @@
 package auth
 
 import (
-	"errors"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
 )
 
-func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+const (
+	ScopeReadBookings = "read:bookings"
+	ScopeWriteConfirm = "write:confirm"
+)
+
+// APIKeyVerifier returns the principal of a personal API key, with the
+// scopes of the key.
+type APIKeyVerifier interface {
+	VerifyAPIKey(key string) (*Principal, error)
+}
+
+// TokenRevocations tells whether a token of the user was revoked, by its ID
+// or by the revocation of every token issued to the user until then.
+type TokenRevocations interface {
+	IsRevoked(userId uint, tokenId string, issuedAt time.Time) bool
+}
+
+// Authenticator provides the middlewares authenticating the users of the
+// service with their access token, or their API key on the routes allowing
+// it.
+type Authenticator struct {
+	verifier    *Verifier
+	apiKeys     APIKeyVerifier
+	revocations TokenRevocations
+}
+
+func NewAuthenticator(verifier *Verifier, apiKeys APIKeyVerifier, revocations TokenRevocations) *Authenticator {
+	return &Authenticator{verifier: verifier, apiKeys: apiKeys, revocations: revocations}
+}
+
+func (authenticator *Authenticator) AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		authHeader := c.Request().Header.Get("Authorization")
 		if authHeader == "" {
@@ -21,35 +50,77 @@ func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid authorization header format."})
 		}
 
-		token := parts[1]
+		return authenticator.authenticate(c, parts[1], next)
+	}
+}
 
-		claims, err := validateToken(token)
-		if err != nil {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token."})
+// StreamAuthMiddleware is AuthMiddleware for long-lived connections opened by
+// browsers (EventSource, WebSocket), which cannot set the Authorization
+// header. The token may be sent in the access_token query parameter instead.
+func (authenticator *Authenticator) StreamAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Header.Get("Authorization") != "" {
+			return authenticator.AuthMiddleware(next)(c)
 		}
 
-		userId, ok := claims["UserId"].(float64)
-		if !ok {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token claim."})
+		token := c.QueryParam("access_token")
+		if token == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "You are not logged in."})
 		}
 
-		c.Set("userId", uint(userId))
+		return authenticator.authenticate(c, token, next)
+	}
+}
+
+// ScopedAuthMiddleware is AuthMiddleware for the routes users may also call
+// with a personal API key having the scope, sent in the X-API-Key header or
+// as the Bearer token. Access tokens are not limited by the scope.
+func (authenticator *Authenticator) ScopedAuthMiddleware(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get("X-API-Key")
+			if key == "" {
+				token, found := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+				// JWTs have three parts separated by dots, API keys none.
+				if found && strings.Count(token, ".") != 2 {
+					key = token
+				}
+			}
+			if key == "" {
+				return authenticator.AuthMiddleware(next)(c)
+			}
+
+			principal, err := authenticator.apiKeys.VerifyAPIKey(key)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid, expired or revoked API key."})
+			}
+			if !principal.HasScope(scope) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "the API key lacks the " + scope + " scope."})
+			}
 
-		return next(c)
+			setPrincipal(c, principal)
+			return next(c)
+		}
 	}
 }
 
-func validateToken(tokenStr string) (jwt.MapClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte("my_secret_key"), nil
-	})
+func (authenticator *Authenticator) authenticate(c echo.Context, token string, next echo.HandlerFunc) error {
+	claims, err := authenticator.verifier.Verify(token)
 	if err != nil {
-		return nil, err
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token."})
+	}
+
+	userId, ok := claims["UserId"].(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token claim."})
 	}
 
-	claims, ok := token.Claims.(*jwt.MapClaims)
-	if !ok || !token.Valid {
-		return nil, errors.New("invalid token")
+	principal := newPrincipal(uint(userId), claims)
+	if principal.IsRevoked(authenticator.revocations) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token."})
 	}
-	return *claims, nil
+
+	setPrincipal(c, principal)
+
+	return next(c)
 }
@@ -0,0 +1,31 @@
+package auth
+
+import "time"
+
+// Config tells the Verifier which tokens to accept. Tokens are signed either
+// with the HMAC secret shared with the users service, or with private keys
+// whose public halves are given as PEM files or published in a JWKS.
+type Config struct {
+	HMACSecret     string        `yaml:"hmacSecret" toml:"hmacSecret" env:"JWT_HMAC_SECRET" secret:"true"`
+	PublicKeyFiles []string      `yaml:"publicKeyFiles" toml:"publicKeyFiles" env:"JWT_PUBLIC_KEY_FILES"`
+	JWKSURL        string        `yaml:"jwksUrl" toml:"jwksUrl" env:"JWT_JWKS_URL" validate:"omitempty,url"`
+	JWKSFile       string        `yaml:"jwksFile" toml:"jwksFile" env:"JWT_JWKS_FILE"`
+	JWKSRefresh    time.Duration `yaml:"jwksRefresh" toml:"jwksRefresh" env:"JWT_JWKS_REFRESH" validate:"min=0"`
+
+	// Algorithms defaults to HS256 with an HMAC secret and RS256 and ES256
+	// with public keys.
+	Algorithms []string      `yaml:"algorithms" toml:"algorithms" env:"JWT_ALGORITHMS"`
+	Issuer     string        `yaml:"issuer" toml:"issuer" env:"JWT_ISSUER"`
+	Audience   string        `yaml:"audience" toml:"audience" env:"JWT_AUDIENCE"`
+	ClockSkew  time.Duration `yaml:"clockSkew" toml:"clockSkew" env:"JWT_CLOCK_SKEW" validate:"min=0"`
+
+	// ServiceAudience is the audience of the tokens other services use to call
+	// the internal routes; user tokens are never accepted there and service
+	// tokens never as user tokens. Services may also authenticate with a
+	// client certificate signed by ClientCAFile. Both are limited to the
+	// Services names (the token subject or the certificate common name), so
+	// at least one service has to be allowed.
+	ServiceAudience string   `yaml:"serviceAudience" toml:"serviceAudience" env:"JWT_SERVICE_AUDIENCE" validate:"required"`
+	Services        []string `yaml:"services" toml:"services" env:"INTERNAL_SERVICES" validate:"min=1,dive,required"`
+	ClientCAFile    string   `yaml:"clientCaFile" toml:"clientCaFile" env:"INTERNAL_CLIENT_CA_FILE"`
+}
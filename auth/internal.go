@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// InternalMiddleware authenticates the other services calling the internal
+// routes, with a client certificate signed by the internal CA or a service
+// token in the Authorization header. End-user tokens are rejected.
+func (authenticator *Authenticator) InternalMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Verified chains are only set when the server asks for client
+		// certificates signed by ClientCAFile, see ServerTLSConfig.
+		if tlsState := c.Request().TLS; tlsState != nil && len(tlsState.VerifiedChains) > 0 {
+			return authenticator.authenticateService(c, tlsState.VerifiedChains[0][0].Subject.CommonName, next)
+		}
+
+		authHeader := c.Request().Header.Get("Authorization")
+		token, found := strings.CutPrefix(authHeader, "Bearer ")
+		if !found || token == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "service credentials are required."})
+		}
+
+		claims, err := authenticator.verifier.VerifyService(token)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token."})
+		}
+
+		service, _ := claims["sub"].(string)
+		return authenticator.authenticateService(c, service, next)
+	}
+}
+
+func (authenticator *Authenticator) authenticateService(c echo.Context, service string, next echo.HandlerFunc) error {
+	// No service is allowed unless listed.
+	if service == "" || !contains(authenticator.verifier.config.Services, service) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "forbidden Access"})
+	}
+
+	c.Set(principalKey, &Principal{Service: service})
+	return next(c)
+}
+
+// ServerTLSConfig serves the certificate of certFile and keyFile. With a
+// ClientCAFile, clients may present a certificate signed by it to call the
+// internal routes; browsers keep connecting without one.
+func ServerTLSConfig(config Config, certFile, keyFile string) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if config.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(config.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no certificate found in " + config.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, nil
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// jwksMinRefetch limits how often an unknown key ID triggers a refetch, so
+// tokens with made-up key IDs cannot make us hammer the JWKS endpoint.
+const jwksMinRefetch = 30 * time.Second
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksCache keeps the keys of a JWKS by key ID. Keys are fetched again when
+// they are older than the refresh interval, or when a token uses a key ID we
+// do not know yet because the issuer rotated its keys. A single fetch runs at
+// a time, outside the lock, so verifying tokens with known keys never waits
+// for the JWKS endpoint.
+type jwksCache struct {
+	url     string
+	file    string
+	refresh time.Duration
+	client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	loading   *jwksLoad
+}
+
+// jwksLoad is a fetch in flight; done is closed once it is over.
+type jwksLoad struct {
+	done chan struct{}
+	err  error
+}
+
+func newJWKSCache(url, file string, refresh time.Duration) *jwksCache {
+	return &jwksCache{url: url, file: file, refresh: refresh, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (cache *jwksCache) key(kid string) (crypto.PublicKey, error) {
+	cache.mu.Lock()
+	key, known := cache.keys[kid]
+	stale := time.Since(cache.fetchedAt) > cache.refresh
+	if (stale || !known) && time.Since(cache.fetchedAt) > jwksMinRefetch {
+		load := cache.startLoadLocked()
+		if !known {
+			// Only tokens signed with a key we do not have wait for the fetch.
+			cache.mu.Unlock()
+			<-load.done
+			cache.mu.Lock()
+			key, known = cache.keys[kid]
+		}
+	}
+	cache.mu.Unlock()
+
+	if !known {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	return key, nil
+}
+
+func (cache *jwksCache) load() error {
+	cache.mu.Lock()
+	load := cache.startLoadLocked()
+	cache.mu.Unlock()
+
+	<-load.done
+	return load.err
+}
+
+// startLoadLocked starts fetching the keys unless a fetch is already in
+// flight, and returns it.
+func (cache *jwksCache) startLoadLocked() *jwksLoad {
+	if cache.loading != nil {
+		return cache.loading
+	}
+	load := &jwksLoad{done: make(chan struct{})}
+	cache.loading = load
+	cache.fetchedAt = time.Now()
+
+	go func() {
+		keys, err := cache.read()
+
+		cache.mu.Lock()
+		if err != nil {
+			// Keep using the keys we have while the JWKS is unreachable.
+			zap.L().Error("error refreshing JWKS", zap.Error(err))
+		} else {
+			cache.keys = keys
+		}
+		cache.loading = nil
+		cache.mu.Unlock()
+
+		load.err = err
+		close(load.done)
+	}()
+	return load
+}
+
+func (cache *jwksCache) read() (map[string]crypto.PublicKey, error) {
+	var body []byte
+	var err error
+	if cache.file != "" {
+		body, err = os.ReadFile(cache.file)
+	} else {
+		body, err = cache.fetch()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(body)
+}
+
+func (cache *jwksCache) fetch() ([]byte, error) {
+	response, err := cache.client.Get(cache.url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("an error occurred: status code %d", response.StatusCode)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// parseJWKS reads the RSA and EC signing keys of a JWKS and skips the others.
+func parseJWKS(body []byte) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := json.Unmarshal(body, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		switch jwk.Kty {
+		case "RSA":
+			key, err = rsaKey(jwk)
+		case "EC":
+			key, err = ecKey(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in JWKS: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func rsaKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func ecKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return key, nil
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+)
+
+// Principal is the authenticated caller. Handlers keep reading the user ID
+// from "userId"; the principal is for decisions based on roles and scopes.
+// Other services calling the internal routes only have a Service name, and
+// users calling with an API key have its APIKeyID and scopes. TokenID (the
+// jti claim), IssuedAt and ExpiresAt describe the access token, for revoking
+// it.
+type Principal struct {
+	UserID    uint
+	Service   string
+	APIKeyID  uint
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Roles     []string
+	Scopes    []string
+}
+
+// IsRevoked tells whether the access token of the principal is revoked, which
+// long-lived connections check again after authenticating. Services and API
+// keys are not authenticated with revocable tokens.
+func (principal *Principal) IsRevoked(revocations TokenRevocations) bool {
+	if principal.Service != "" || principal.APIKeyID != 0 {
+		return false
+	}
+	return revocations.IsRevoked(principal.UserID, principal.TokenID, principal.IssuedAt)
+}
+
+func (principal *Principal) HasRole(role string) bool {
+	return contains(principal.Roles, role)
+}
+
+func (principal *Principal) HasScope(scope string) bool {
+	return contains(principal.Scopes, scope)
+}
+
+const principalKey = "principal"
+
+// GetPrincipal returns the principal set by the auth middlewares.
+func GetPrincipal(c echo.Context) (*Principal, bool) {
+	principal, ok := c.Get(principalKey).(*Principal)
+	return principal, ok
+}
+
+func setPrincipal(c echo.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+	c.Set("userId", principal.UserID)
+}
+
+// RequireRole lets through the callers having at least one of the roles. It
+// runs after AuthMiddleware.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := GetPrincipal(c)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "You are not logged in."})
+			}
+
+			for _, role := range roles {
+				if principal.HasRole(role) {
+					return next(c)
+				}
+			}
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "forbidden Access"})
+		}
+	}
+}
+
+// newPrincipal reads the roles from the "roles" claim (a list, or a string
+// separated by spaces or commas) and the scopes from the OAuth "scope" claim
+// or a "scopes" list.
+func newPrincipal(userId uint, claims jwt.MapClaims) *Principal {
+	tokenId, _ := claims["jti"].(string)
+	return &Principal{
+		UserID:    userId,
+		TokenID:   tokenId,
+		IssuedAt:  claimTime(claims["iat"]),
+		ExpiresAt: claimTime(claims["exp"]),
+		Roles:     claimList(claims["roles"]),
+		Scopes:    append(claimList(claims["scope"]), claimList(claims["scopes"])...),
+	}
+}
+
+func claimTime(claim interface{}) time.Time {
+	seconds, ok := claim.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+func claimList(claim interface{}) []string {
+	var list []string
+	switch value := claim.(type) {
+	case string:
+		list = strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	case []interface{}:
+		for _, item := range value {
+			if str, ok := item.(string); ok && str != "" {
+				list = append(list, str)
+			}
+		}
+	}
+	return list
+}
+
+func contains(list []string, item string) bool {
+	for _, listItem := range list {
+		if listItem == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+)
+
+// Verifier checks the signature and the claims of access tokens.
+type Verifier struct {
+	config Config
+	parser *jwt.Parser
+
+	// staticKeys holds the keys of PublicKeyFiles by file name without its
+	// extension, which tokens refer to as their key ID.
+	staticKeys map[string]crypto.PublicKey
+	jwks       *jwksCache
+}
+
+func NewVerifier(config Config) (*Verifier, error) {
+	if config.HMACSecret == "" && len(config.PublicKeyFiles) == 0 && config.JWKSURL == "" && config.JWKSFile == "" {
+		return nil, errors.New("no JWT key configured: set JWT_HMAC_SECRET, JWT_PUBLIC_KEY_FILES, JWT_JWKS_URL or JWT_JWKS_FILE")
+	}
+	if config.JWKSURL != "" && config.JWKSFile != "" {
+		return nil, errors.New("JWT_JWKS_URL and JWT_JWKS_FILE cannot both be set")
+	}
+
+	if len(config.Algorithms) == 0 {
+		if config.HMACSecret != "" {
+			config.Algorithms = append(config.Algorithms, "HS256")
+		}
+		if len(config.PublicKeyFiles) > 0 || config.JWKSURL != "" || config.JWKSFile != "" {
+			config.Algorithms = append(config.Algorithms, "RS256", "ES256")
+		}
+	}
+	for _, algorithm := range config.Algorithms {
+		method := jwt.GetSigningMethod(algorithm)
+		if method == nil || method == jwt.SigningMethodNone {
+			return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+		}
+		if _, ok := method.(*jwt.SigningMethodHMAC); ok && config.HMACSecret == "" {
+			return nil, fmt.Errorf("JWT algorithm %s needs JWT_HMAC_SECRET", algorithm)
+		}
+	}
+
+	verifier := &Verifier{
+		config:     config,
+		parser:     jwt.NewParser(jwt.WithValidMethods(config.Algorithms), jwt.WithoutClaimsValidation()),
+		staticKeys: map[string]crypto.PublicKey{},
+	}
+
+	for _, path := range config.PublicKeyFiles {
+		key, err := readPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading JWT public key %s: %w", path, err)
+		}
+		verifier.staticKeys[strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))] = key
+	}
+
+	if config.JWKSURL != "" || config.JWKSFile != "" {
+		verifier.jwks = newJWKSCache(config.JWKSURL, config.JWKSFile, config.JWKSRefresh)
+		err := verifier.jwks.load()
+		if err != nil {
+			// A missing file is a deployment mistake, but the identity
+			// provider being down should not stop us from starting.
+			if config.JWKSFile != "" {
+				return nil, fmt.Errorf("error reading JWKS: %w", err)
+			}
+			zap.L().Error("error fetching JWKS, retrying on the first token", zap.Error(err))
+		}
+	}
+	return verifier, nil
+}
+
+// Verify returns the claims of a user token signed with one of the allowed
+// algorithms by a configured key. The token must expire, and exp, nbf and iat
+// are checked with the configured clock skew.
+func (verifier *Verifier) Verify(tokenStr string) (jwt.MapClaims, error) {
+	claims, err := verifier.verify(tokenStr, verifier.config.Issuer, verifier.config.Audience)
+	if err != nil {
+		return nil, err
+	}
+	if claims.VerifyAudience(verifier.config.ServiceAudience, true) {
+		return nil, errors.New("service tokens cannot authenticate users")
+	}
+	return claims, nil
+}
+
+// VerifyService is Verify for the tokens of other services, which must be for
+// the service audience. Their issuer is not checked: each service signs its
+// own tokens, and is trusted through its key.
+func (verifier *Verifier) VerifyService(tokenStr string) (jwt.MapClaims, error) {
+	return verifier.verify(tokenStr, "", verifier.config.ServiceAudience)
+}
+
+func (verifier *Verifier) verify(tokenStr, issuer, audience string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := verifier.parser.ParseWithClaims(tokenStr, claims, verifier.key)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	now := time.Now()
+	skew := verifier.config.ClockSkew
+	if !claims.VerifyExpiresAt(now.Add(-skew).Unix(), true) {
+		return nil, errors.New("token is expired")
+	}
+	if !claims.VerifyNotBefore(now.Add(skew).Unix(), false) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if !claims.VerifyIssuedAt(now.Add(skew).Unix(), false) {
+		return nil, errors.New("token used before issued")
+	}
+	if issuer != "" && !claims.VerifyIssuer(issuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if audience != "" && !claims.VerifyAudience(audience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+	return claims, nil
+}
+
+// key returns the key checking the signature of the token. The parser already
+// made sure the algorithm is allowed; the key type must also match it, so an
+// RSA public key can never be used as an HMAC secret.
+func (verifier *Verifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return []byte(verifier.config.HMACSecret), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return verifier.publicKey(token, func(key crypto.PublicKey) bool {
+			_, ok := key.(*rsa.PublicKey)
+			return ok
+		})
+	case *jwt.SigningMethodECDSA:
+		return verifier.publicKey(token, func(key crypto.PublicKey) bool {
+			_, ok := key.(*ecdsa.PublicKey)
+			return ok
+		})
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+// publicKey finds the key of the token's key ID in the public key files, then
+// in the JWKS. Tokens without a key ID are accepted when a single key file of
+// the right type is configured.
+func (verifier *Verifier) publicKey(token *jwt.Token, matches func(crypto.PublicKey) bool) (crypto.PublicKey, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	var key crypto.PublicKey
+	if kid == "" {
+		for _, staticKey := range verifier.staticKeys {
+			if !matches(staticKey) {
+				continue
+			}
+			if key != nil {
+				return nil, errors.New("token has no key ID")
+			}
+			key = staticKey
+		}
+	} else if staticKey, ok := verifier.staticKeys[kid]; ok {
+		key = staticKey
+	} else if verifier.jwks != nil {
+		jwksKey, err := verifier.jwks.key(kid)
+		if err != nil {
+			return nil, err
+		}
+		key = jwksKey
+	}
+
+	if key == nil || !matches(key) {
+		return nil, errors.New("no key for token")
+	}
+	return key, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(pem); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("not an RSA or ECDSA public key")
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rental_service/config"
+	"rental_service/rent"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/fx"
+)
+
+const usage = `usage: rental_service <command> [arguments]
+
+commands:
+  serve                                   run the HTTP server and workers (default)
+  migrate up | down [steps] | status | goto <version>
+  migrate force <version>                 mark the migrations up to version as applied without running them
+  requests list [filters]                 search the rent requests
+  requests show <id>                      show a rent request and its admin actions
+  requests transition <id> -admin <id> -status <status> -reason <reason>
+  payments reconcile -file <export>       compare the payment gateway export with the rent requests
+  seed                                    create sample rent requests for development
+
+Commands printing results take -output table|json.`
+
+var commands = map[string]func(cfg *config.Config, args []string) error{
+	"serve":    runServe,
+	"migrate":  runMigrate,
+	"requests": runRequests,
+	"payments": runPayments,
+	"seed":     runSeed,
+}
+
+// runCommand builds the components the command populates from the same
+// graph as the server, without the server and workers, and runs it.
+func runCommand(cfg *config.Config, command func() error, targets ...interface{}) error {
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(cfg),
+		providers,
+		fx.Populate(targets...),
+	)
+	if err := app.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+	defer app.Stop(context.Background())
+
+	return command()
+}
+
+// parseArgs parses the flags of a subcommand taking one positional argument,
+// which may come before or after the flags.
+func parseArgs(flags *flag.FlagSet, args []string) (string, error) {
+	var arg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		arg, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if arg == "" && flags.NArg() > 0 {
+		arg = flags.Arg(0)
+	}
+	if arg == "" {
+		return "", fmt.Errorf("%s needs an argument", flags.Name())
+	}
+	return arg, nil
+}
+
+func runRequests(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "list":
+		return runRequestsList(cfg, args[1:])
+	case "show":
+		return runRequestsShow(cfg, args[1:])
+	case "transition":
+		return runRequestsTransition(cfg, args[1:])
+	default:
+		return errors.New(usage)
+	}
+}
+
+func runRequestsList(cfg *config.Config, args []string) error {
+	var searchDto rent.AdminSearchDto
+	flags := flag.NewFlagSet("requests list", flag.ContinueOnError)
+	flags.StringVar(&searchDto.Status, "status", "", "status of the rent requests")
+	flags.StringVar(&searchDto.PaymentStatus, "payment-status", "", "payment status of the rent requests")
+	flags.UintVar(&searchDto.RenterID, "renter", 0, "renter ID")
+	flags.UintVar(&searchDto.OwnerID, "owner", 0, "owner ID")
+	flags.UintVar(&searchDto.PostID, "post", 0, "post ID")
+	flags.UintVar(&searchDto.BookingGroupID, "group", 0, "booking group ID")
+	flags.StringVar(&searchDto.Date, "date", "", "creation date range, min,max")
+	flags.IntVar(&searchDto.Page, "page", 1, "page")
+	flags.IntVar(&searchDto.PageSize, "page-size", 20, "rent requests per page")
+	format := outputFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var service *rent.RentService
+	var validate *validator.Validate
+	return runCommand(cfg, func() error {
+		if err := validate.Struct(searchDto); err != nil {
+			return err
+		}
+
+		result, err := service.SearchRentRequests(searchDto)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(result.RentRequests))
+		for _, rentRequest := range result.RentRequests {
+			rows = append(rows, rentRequestRow(rentRequest))
+		}
+		err = printResult(*format, result, rentRequestHeader, rows)
+		if err == nil && *format == "table" {
+			fmt.Printf("%d of %d rent requests\n", len(rows), result.Total)
+		}
+		return err
+	}, &service, &validate)
+}
+
+var rentRequestHeader = []string{"ID", "RENTER", "OWNER", "POST", "GROUP", "START", "END", "QUANTITY", "TOTAL", "STATUS", "PAYMENT", "CREATED"}
+
+func rentRequestRow(rentRequest rent.AdminRentRequestResponse) []string {
+	return []string{
+		fmt.Sprint(rentRequest.ID),
+		fmt.Sprint(rentRequest.RenterID),
+		fmt.Sprint(rentRequest.OwnerID),
+		fmt.Sprint(rentRequest.PostID),
+		formatID(rentRequest.BookingGroupID),
+		formatTime(rentRequest.StartDate),
+		formatTime(rentRequest.EndDate),
+		fmt.Sprint(rentRequest.Quantity),
+		fmt.Sprint(rentRequest.TotalPrice),
+		rentRequest.Status,
+		rentRequest.PaymentStatus,
+		formatTime(rentRequest.CreatedAt),
+	}
+}
+
+func runRequestsShow(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("requests show", flag.ContinueOnError)
+	format := outputFlag(flags)
+	rentRequestIdStr, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var service *rent.RentService
+	return runCommand(cfg, func() error {
+		rentRequest, err := service.GetAdminRentRequest(rentRequestIdStr)
+		if err != nil {
+			return err
+		}
+		return printRentRequest(*format, rentRequest)
+	}, &service)
+}
+
+func runRequestsTransition(cfg *config.Config, args []string) error {
+	var transitionDto rent.TransitionDto
+	flags := flag.NewFlagSet("requests transition", flag.ContinueOnError)
+	adminId := flags.Uint("admin", 0, "user ID of the admin making the change, kept with the action")
+	flags.StringVar(&transitionDto.Status, "status", "", "new status")
+	flags.StringVar(&transitionDto.PaymentStatus, "payment-status", "", "new payment status")
+	flags.StringVar(&transitionDto.Reason, "reason", "", "reason of the change, at least 10 characters")
+	format := outputFlag(flags)
+	rentRequestIdStr, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if *adminId == 0 {
+		return errors.New("requests transition needs -admin")
+	}
+	transitionDto.Reason = strings.TrimSpace(transitionDto.Reason)
+
+	var service *rent.RentService
+	var validate *validator.Validate
+	return runCommand(cfg, func() error {
+		if err := validate.Struct(transitionDto); err != nil {
+			return err
+		}
+
+		rentRequest, err := service.TransitionRentRequest(*adminId, rentRequestIdStr, transitionDto)
+		if err != nil {
+			return err
+		}
+		return printRentRequest(*format, rentRequest)
+	}, &service, &validate)
+}
+
+func printRentRequest(format outputFormat, rentRequest *rent.AdminRentRequestResponse) error {
+	err := printResult(format, rentRequest, rentRequestHeader, [][]string{rentRequestRow(*rentRequest)})
+	if err != nil || format != "table" || len(rentRequest.Actions) == 0 {
+		return err
+	}
+
+	fmt.Println()
+	rows := make([][]string, 0, len(rentRequest.Actions))
+	for _, action := range rentRequest.Actions {
+		rows = append(rows, []string{
+			formatTime(action.CreatedAt),
+			fmt.Sprint(action.AdminID),
+			action.FromStatus + " -> " + action.ToStatus,
+			action.FromPaymentStatus + " -> " + action.ToPaymentStatus,
+			action.Reason,
+		})
+	}
+	return printTable([]string{"AT", "ADMIN", "STATUS", "PAYMENT", "REASON"}, rows)
+}
+
+func runPayments(cfg *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "reconcile" {
+		return errors.New(usage)
+	}
+
+	flags := flag.NewFlagSet("payments reconcile", flag.ContinueOnError)
+	file := flags.String("file", "", "payment gateway export, a JSON array or a CSV with requestId,status columns")
+	apply := flags.Bool("apply", false, "replay the missed callbacks instead of only reporting them")
+	format := outputFlag(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("payments reconcile needs -file")
+	}
+
+	payments, err := readGatewayPayments(*file)
+	if err != nil {
+		return err
+	}
+
+	var service *rent.RentService
+	var validate *validator.Validate
+	return runCommand(cfg, func() error {
+		for _, payment := range payments {
+			if err := validate.Struct(payment); err != nil {
+				return fmt.Errorf("rent request %d: %w", payment.RentRequestID, err)
+			}
+		}
+
+		results, err := service.ReconcilePayments(payments, *apply)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(results))
+		for _, result := range results {
+			rows = append(rows, []string{
+				fmt.Sprint(result.RentRequestID),
+				result.GatewayStatus,
+				result.Status,
+				result.PaymentStatus,
+				result.Action,
+				strconv.FormatBool(result.Applied),
+			})
+		}
+		return printResult(*format, results, []string{"ID", "GATEWAY", "STATUS", "PAYMENT", "ACTION", "APPLIED"}, rows)
+	}, &service, &validate)
+}
+
+func readGatewayPayments(path string) ([]rent.GatewayPayment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var payments []rent.GatewayPayment
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.NewDecoder(file).Decode(&payments)
+		return payments, err
+	}
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	idColumn, ok := columns["requestId"]
+	statusColumn, found := columns["status"]
+	if !ok || !found {
+		return nil, errors.New("the CSV export needs requestId and status columns")
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return payments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rentRequestId, err := strconv.ParseUint(strings.TrimSpace(record[idColumn]), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid requestId %q", record[idColumn])
+		}
+		payments = append(payments, rent.GatewayPayment{RentRequestID: uint(rentRequestId), Status: strings.TrimSpace(record[statusColumn])})
+	}
+}
+
+// seedStatuses is the cycle of states given to the seeded rent requests.
+var seedStatuses = []struct{ status, paymentStatus string }{
+	{"waiting for confirmation", "pending"},
+	{"Confirmed", "pending"},
+	{"paid", "success"},
+	{"Rejected", "pending"},
+	{"canceled", "pending"},
+}
+
+func runSeed(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	count := flags.Int("count", 10, "number of rent requests")
+	renterId := flags.Uint("renter", 1, "renter ID")
+	ownerId := flags.Uint("owner", 2, "owner ID")
+	postId := flags.Uint("post", 1, "post ID")
+	price := flags.Int("price", 100, "price per day")
+	format := outputFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return errors.New("seed needs a positive -count")
+	}
+
+	var repo *rent.RentRepository
+	return runCommand(cfg, func() error {
+		// Consecutive two-day stays starting next week, so the paid ones do
+		// not overlap.
+		start := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7)
+		rentRequests := make([]rent.RentRequest, 0, *count)
+		rows := make([][]string, 0, *count)
+		for i := 0; i < *count; i++ {
+			state := seedStatuses[i%len(seedStatuses)]
+			rentRequest := rent.RentRequest{
+				RenterID:      *renterId,
+				OwnerID:       *ownerId,
+				PostID:        *postId,
+				StartDate:     start.AddDate(0, 0, 2*i),
+				EndDate:       start.AddDate(0, 0, 2*i+2),
+				Quantity:      1,
+				TotalPrice:    2 * *price,
+				Status:        state.status,
+				PaymentStatus: state.paymentStatus,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			}
+			if err := repo.AddRentRequest(&rentRequest); err != nil {
+				return err
+			}
+			rentRequests = append(rentRequests, rentRequest)
+			rows = append(rows, []string{fmt.Sprint(rentRequest.ID), formatTime(rentRequest.StartDate), formatTime(rentRequest.EndDate), rentRequest.Status, rentRequest.PaymentStatus})
+		}
+		return printResult(*format, rentRequests, []string{"ID", "START", "END", "STATUS", "PAYMENT"}, rows)
+	}, &repo)
+}
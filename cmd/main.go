@@ -1,9 +1,26 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"rental_service/apikey"
 	"rental_service/auth"
+	"rental_service/config"
+	"rental_service/events"
+	"rental_service/metrics"
+	"rental_service/migrations"
+	"rental_service/notification"
+	"rental_service/realtime"
 	"rental_service/rent"
+	"rental_service/revocation"
+	"rental_service/webhook"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -14,70 +31,346 @@ import (
 	"gorm.io/gorm"
 )
 
-func NewDB() (*gorm.DB, error) {
-	dsn := "host=localhost user=admin password=sahar223010 dbname=rental_service_db search_path=rent-request-service port=5432 sslmode=disable"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// NewDB closes the pool when the app stops. It is built before everything
+// using it, so its hook runs last.
+func NewDB(lc fx.Lifecycle, cfg *config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.Database.DSN()), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	err = metrics.RegisterDB(sqlDB, cfg.Database.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return sqlDB.Close()
+		},
+	})
 	return db, nil
 }
 
-// func NewLogger() (*zap.Logger, error) {
-// 	logger, err := zap.NewProduction()
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return logger, nil
-// }
+// NewComponentConfigs hands each component its part of the configuration.
+func NewComponentConfigs(cfg *config.Config) (auth.Config, rent.Config, notification.Config) {
+	return cfg.Auth, cfg.Services, cfg.Notification
+}
+
+func NewEcho(cfg *config.Config) *echo.Echo {
+	e := echo.New()
+
+	e.Use(metrics.Middleware)
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: cfg.Server.CORSOrigins,
+		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
+		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization, "X-API-Key"},
+	}))
+	return e
+}
+
+// NewLogger builds the production logger and installs it as the global one
+// the components log with through zap.L().
+func NewLogger(lc fx.Lifecycle) (*zap.Logger, error) {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		return nil, err
+	}
+	zap.ReplaceGlobals(logger)
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			// Syncing stderr fails on some terminals, there is nothing to do about it.
+			_ = logger.Sync()
+			return nil
+		},
+	})
+	return logger, nil
+}
 
 func NewValidator() *validator.Validate {
 	return validator.New()
 }
 
-func RegisterRoutes(e *echo.Echo, handler *rent.RentHandler) {
+func NewPublisher(cfg *config.Config, webhookService *webhook.WebhookService, notificationService *notification.NotificationService) events.Publisher {
+	publishers := []events.Publisher{events.NewLogPublisher(), webhookService, notificationService}
+	if cfg.Events.WebhookURL != "" {
+		publishers = append(publishers, events.NewWebhookPublisher(cfg.Events.WebhookURL))
+	}
+	return events.NewMultiPublisher(publishers...)
+}
+
+func NewWaitlistNotifier(notificationService *notification.NotificationService) rent.WaitlistNotifier {
+	return notificationService
+}
+
+func NewAPIKeyVerifier(apiKeyService *apikey.APIKeyService) auth.APIKeyVerifier {
+	return apiKeyService
+}
+
+func NewTokenRevocations(revocationService *revocation.RevocationService) auth.TokenRevocations {
+	return revocationService
+}
+
+func NewBroadcaster(hub *realtime.Hub) rent.Broadcaster {
+	return hub
+}
+
+func RegisterRoutes(e *echo.Echo, authenticator *auth.Authenticator, handler *rent.RentHandler, messageHandler *rent.MessageHandler, webhookHandler *webhook.WebhookHandler, notificationHandler *notification.NotificationHandler, streamHandler *realtime.StreamHandler, socketHandler *realtime.SocketHandler, apiKeyHandler *apikey.APIKeyHandler, revocationHandler *revocation.RevocationHandler) {
+	// The scraper authenticates like the other services and has to be listed
+	// in the allowed services.
+	e.GET("/metrics", metrics.Handler(), authenticator.InternalMiddleware)
+
 	rentRequestGroup := e.Group("/rent-request")
-	rentRequestGroup.Use(auth.AuthMiddleware)
+	rentRequestGroup.Use(authenticator.AuthMiddleware)
 	rentRequestGroup.POST("", handler.CreateRentRequest)
-	rentRequestGroup.GET("/:rentRequestId", handler.GetRentRequestById)
-	rentRequestGroup.PUT("/:rentRequestId/confirm", handler.ConfirmRentRequest)
 	rentRequestGroup.POST("/:rentRequestId/pay", handler.PayRentRequest)
 	rentRequestGroup.PUT("/:rentRequestId/cancel", handler.CancelRentRequest)
-	rentRequestGroup.GET("/owner", handler.GetOwnerRentRequests)
-	rentRequestGroup.GET("/renter", handler.GetRenterRentRequests)
+	rentRequestGroup.POST("/:rentRequestId/extend", handler.ExtendRentRequest)
+	rentRequestGroup.GET("/instant-book/:postId", handler.GetInstantBookSetting)
+	rentRequestGroup.PUT("/instant-book/:postId", handler.UpdateInstantBookSetting)
+	rentRequestGroup.GET("/waitlist", handler.GetWaitlist)
+	rentRequestGroup.POST("/waitlist", handler.JoinWaitlist)
+	rentRequestGroup.DELETE("/waitlist/:waitlistId", handler.LeaveWaitlist)
+	rentRequestGroup.POST("/bundle", handler.CreateBundle)
+	rentRequestGroup.GET("/bundle/:groupId", handler.GetBundle)
+	rentRequestGroup.POST("/bundle/:groupId/pay", handler.PayBundle)
+	rentRequestGroup.PUT("/bundle/:groupId/cancel", handler.CancelBundle)
+	rentRequestGroup.GET("/:rentRequestId/messages", messageHandler.GetMessages)
+	rentRequestGroup.POST("/:rentRequestId/messages", messageHandler.SendMessage)
+	rentRequestGroup.GET("/:rentRequestId/offers", handler.GetOffers)
+	rentRequestGroup.POST("/:rentRequestId/offers", handler.ProposeOffer)
+	rentRequestGroup.PUT("/:rentRequestId/offers/:offerId/accept", handler.AcceptOffer)
+	rentRequestGroup.PUT("/:rentRequestId/offers/:offerId/decline", handler.DeclineOffer)
+	rentRequestGroup.GET("/:rentRequestId/modifications", handler.GetModifications)
+	rentRequestGroup.POST("/:rentRequestId/modifications", handler.ProposeModification)
+	rentRequestGroup.PUT("/:rentRequestId/modifications/:modificationId/approve", handler.ApproveModification)
+	rentRequestGroup.PUT("/:rentRequestId/modifications/:modificationId/reject", handler.RejectModification)
+	rentRequestGroup.POST("/:rentRequestId/modifications/:modificationId/pay", handler.PayModification)
+	rentRequestGroup.GET("/webhooks", webhookHandler.GetSubscriptions)
+	rentRequestGroup.POST("/webhooks", webhookHandler.CreateSubscription)
+	rentRequestGroup.DELETE("/webhooks/:subscriptionId", webhookHandler.DeleteSubscription)
+	rentRequestGroup.GET("/webhooks/:subscriptionId/deliveries", webhookHandler.GetDeliveries)
+	rentRequestGroup.POST("/webhooks/:subscriptionId/deliveries/:deliveryId/replay", webhookHandler.ReplayDelivery)
+	rentRequestGroup.GET("/notification-preferences", notificationHandler.GetPreference)
+	rentRequestGroup.PUT("/notification-preferences", notificationHandler.UpdatePreference)
+	rentRequestGroup.GET("/api-keys", apiKeyHandler.GetAPIKeys)
+	rentRequestGroup.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+	rentRequestGroup.DELETE("/api-keys/:apiKeyId", apiKeyHandler.RevokeAPIKey)
+	rentRequestGroup.POST("/logout", revocationHandler.Logout)
+
+	// Routes owners may also automate with their personal API keys.
+	e.GET("/rent-request/:rentRequestId", handler.GetRentRequestById, authenticator.ScopedAuthMiddleware(auth.ScopeReadBookings))
+	e.PUT("/rent-request/:rentRequestId/confirm", handler.ConfirmRentRequest, authenticator.ScopedAuthMiddleware(auth.ScopeWriteConfirm))
+	e.GET("/rent-request/owner", handler.GetOwnerRentRequests, authenticator.ScopedAuthMiddleware(auth.ScopeReadBookings))
+	e.GET("/rent-request/renter", handler.GetRenterRentRequests, authenticator.ScopedAuthMiddleware(auth.ScopeReadBookings))
 
-	e.GET("/rent-request/callback", handler.UpdateRentRequestPaymentStatus)
+	adminGroup := e.Group("/admin/rent-requests")
+	adminGroup.Use(authenticator.AuthMiddleware, auth.RequireRole(auth.RoleAdmin, auth.RoleSupport))
+	adminGroup.GET("", handler.SearchRentRequests)
+	adminGroup.GET("/:rentRequestId", handler.GetAdminRentRequest)
+	adminGroup.POST("/:rentRequestId/transition", handler.TransitionRentRequest, auth.RequireRole(auth.RoleAdmin))
+
+	e.POST("/admin/users/:userId/revoke-sessions", revocationHandler.RevokeUserSessions, authenticator.AuthMiddleware, auth.RequireRole(auth.RoleAdmin))
+	e.POST("/admin/tokens/revoke", revocationHandler.RevokeToken, authenticator.AuthMiddleware, auth.RequireRole(auth.RoleAdmin))
+
+	e.GET("/rent-request/stream", streamHandler.Stream, authenticator.StreamAuthMiddleware)
+	e.GET("/rent-request/ws", socketHandler.Connect, authenticator.StreamAuthMiddleware)
+
+	// The payment service calls back with its service credentials.
+	e.GET("/rent-request/callback", handler.UpdateRentRequestPaymentStatus, authenticator.InternalMiddleware)
+	e.GET("/rent-request/modification-callback", handler.UpdateModificationPaymentStatus, authenticator.InternalMiddleware)
+	e.GET("/rent-request/bundle/callback", handler.UpdateBundlePaymentStatus, authenticator.InternalMiddleware)
+
+	internalGroup := e.Group("/internal")
+	internalGroup.Use(authenticator.InternalMiddleware)
+	internalGroup.GET("/rent-requests/:rentRequestId", handler.GetAdminRentRequest)
+	internalGroup.GET("/posts/:postId/bookings", handler.GetPostBookings)
 }
 
-func main() {
-	e := echo.New()
+// RegisterWorkers starts the background workers with the app. They stop in
+// order: the rent request workers and the outbox relay first, as they produce
+// the events, then the webhook deliveries those events become.
+func RegisterWorkers(lc fx.Lifecycle, cfg *config.Config, rentService *rent.RentService, relay *events.Relay, webhookService *webhook.WebhookService, revocationService *revocation.RevocationService) {
+	workers := &Workers{}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			workers.Go("waitlist expiry", func(ctx context.Context) {
+				rentService.RunWaitlistExpiry(ctx, time.Minute)
+			})
+			workers.Go("rent request completion", func(ctx context.Context) {
+				rentService.RunRentRequestCompletion(ctx, time.Hour)
+			})
+			workers.Go("outbox relay", func(ctx context.Context) {
+				relay.Run(ctx, cfg.Events.RelayInterval)
+			})
+			workers.Go("webhook deliveries", func(ctx context.Context) {
+				webhookService.RunDeliveries(ctx, 10*time.Second)
+			})
+			workers.Go("token revocations", func(ctx context.Context) {
+				revocationService.Run(ctx, 30*time.Second)
+			})
+			return nil
+		},
+		OnStop: workers.Stop,
+	})
+}
 
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"http://localhost:8083"},
-		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
-		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization},
-	}))
+// RegisterServer listens when the app starts, serving HTTPS when a TLS
+// certificate is configured, which internal services need to authenticate
+// with client certificates. On stop the open streams are closed and the
+// in-flight requests get cfg.Server.ShutdownTimeout to finish.
+func RegisterServer(lc fx.Lifecycle, shutdowner fx.Shutdowner, e *echo.Echo, cfg *config.Config, hub *realtime.Hub) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			server := e.Server
+			var tlsConfig *tls.Config
+			if cfg.Server.TLSCertFile == "" {
+				if cfg.Auth.ClientCAFile != "" {
+					return errors.New("INTERNAL_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
+				}
+			} else {
+				var err error
+				tlsConfig, err = auth.ServerTLSConfig(cfg.Auth, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+				if err != nil {
+					return err
+				}
+				server = e.TLSServer
+			}
+
+			// Listening here rather than in the goroutine reports a taken
+			// address as a start failure.
+			listener, err := net.Listen("tcp", cfg.Server.Address)
+			if err != nil {
+				return err
+			}
+			server.Addr = cfg.Server.Address
+			if tlsConfig != nil {
+				server.TLSConfig = tlsConfig
+				e.TLSListener = tls.NewListener(listener, tlsConfig)
+			} else {
+				e.Listener = listener
+			}
+
+			go func() {
+				if err := e.StartServer(server); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					zap.L().Error("error serving http", zap.Error(err))
+					shutdowner.Shutdown(fx.ExitCode(1))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// The streams would otherwise keep the drain waiting; clients
+			// reconnect to another instance and resume from their last event.
+			hub.Close()
+
+			ctx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+			defer cancel()
+			return e.Shutdown(ctx)
+		},
+	})
+}
+
+// providers builds every component of the service. The commands share it
+// with the server and only construct what they use.
+var providers = fx.Provide(
+	NewComponentConfigs,
+	NewDB,
+	migrations.NewMigrator,
+	NewLogger,
+	NewValidator,
+	auth.NewVerifier,
+	auth.NewAuthenticator,
+	apikey.NewAPIKeyRepository,
+	apikey.NewAPIKeyService,
+	apikey.NewAPIKeyHandler,
+	NewAPIKeyVerifier,
+	revocation.NewRevocationRepository,
+	revocation.NewRevocationService,
+	revocation.NewRevocationHandler,
+	NewTokenRevocations,
+	rent.NewServiceClient,
+	rent.NewRentRepository,
+	rent.NewRentService,
+	rent.NewRentHandler,
+	rent.NewMessageRepository,
+	rent.NewMessageService,
+	rent.NewMessageHandler,
+	rent.NewOfferRepository,
+	rent.NewModificationRepository,
+	rent.NewInstantBookRepository,
+	rent.NewWaitlistRepository,
+	rent.NewBundleRepository,
+	NewWaitlistNotifier,
+	events.NewOutboxRepository,
+	events.NewRelay,
+	webhook.NewWebhookRepository,
+	webhook.NewWebhookService,
+	webhook.NewWebhookHandler,
+	notification.NewPreferenceRepository,
+	notification.NewNotificationService,
+	notification.NewNotificationHandler,
+	notification.NewChannels,
+	NewPublisher,
+	realtime.NewHub,
+	realtime.NewStreamHandler,
+	realtime.NewSocketHandler,
+	NewBroadcaster,
+	NewEcho,
+)
+
+// runServe runs the HTTP server and the workers until SIGINT or SIGTERM.
+func runServe(cfg *config.Config, args []string) error {
+	err := cfg.ValidateAuth()
+	if err != nil {
+		return err
+	}
 
 	app := fx.New(
-		fx.Provide(
-			NewDB,
-			// NewLogger,
-			NewValidator,
-			rent.NewRentRepository,
-			rent.NewRentService,
-			rent.NewRentHandler,
-			func() *echo.Echo { return e },
-		),
+		fx.Supply(cfg),
+		// The drain gets ShutdownTimeout, the workers and the database the rest.
+		fx.StopTimeout(2*cfg.Server.ShutdownTimeout),
+		providers,
 		fx.Invoke(
-			func(e *echo.Echo, handler *rent.RentHandler) {
-				RegisterRoutes(e, handler)
+			// Taking the logger installs it before anything else is invoked.
+			func(logger *zap.Logger, cfg *config.Config) {
+				logger.Info("configuration loaded", zap.Any("config", cfg.Redacted()))
 			},
-			func() {
-				if err := e.Start(":8082"); err != nil {
-					log.Fatal("Echo server failed to start", zap.Error(err))
-				}
+			AutoMigrate,
+			func(e *echo.Echo, authenticator *auth.Authenticator, handler *rent.RentHandler, messageHandler *rent.MessageHandler, webhookHandler *webhook.WebhookHandler, notificationHandler *notification.NotificationHandler, streamHandler *realtime.StreamHandler, socketHandler *realtime.SocketHandler, apiKeyHandler *apikey.APIKeyHandler, revocationHandler *revocation.RevocationHandler) {
+				RegisterRoutes(e, authenticator, handler, messageHandler, webhookHandler, notificationHandler, streamHandler, socketHandler, apiKeyHandler, revocationHandler)
 			},
+			RegisterWorkers,
+			RegisterServer,
 		),
 	)
 	app.Run()
+	return nil
+}
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("failed to load configuration: ", err)
+	}
+
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := command(cfg, args[1:]); err != nil {
+		log.Fatal(err)
+	}
 }
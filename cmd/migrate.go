@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"rental_service/config"
+	"rental_service/migrations"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status | goto <version> | force <version>"
+
+// AutoMigrate applies the pending migrations before anything uses the
+// database when cfg.Database.AutoMigrate is set.
+func AutoMigrate(cfg *config.Config, migrator *migrations.Migrator) error {
+	if !cfg.Database.AutoMigrate {
+		return nil
+	}
+	return migrator.Up()
+}
+
+// runMigrate runs a migrate subcommand.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "goto" && args[0] != "force" && args[0] != "status") {
+		return errors.New(migrateUsage)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	format := outputFlag(flags)
+	if args[0] == "status" {
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+	}
+
+	var migrator *migrations.Migrator
+	command := func() error {
+		switch args[0] {
+		case "up":
+			return migrator.Up()
+		case "down":
+			steps := 1
+			if len(args) > 1 {
+				var err error
+				steps, err = strconv.Atoi(args[1])
+				if err != nil || steps < 1 {
+					return errors.New(migrateUsage)
+				}
+			}
+			return migrator.Down(steps)
+		case "goto", "force":
+			if len(args) < 2 {
+				return errors.New(migrateUsage)
+			}
+			version, err := strconv.ParseUint(args[1], 10, 32)
+			if err != nil {
+				return errors.New(migrateUsage)
+			}
+			if args[0] == "force" {
+				// Only rewrites schema_migrations, see Migrator.Force.
+				return migrator.Force(uint(version))
+			}
+			return migrator.Goto(uint(version))
+		case "status":
+			statuses, err := migrator.Status()
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(statuses))
+			for _, status := range statuses {
+				appliedAt := "pending"
+				if status.Applied {
+					appliedAt = formatTime(*status.AppliedAt)
+				}
+				rows = append(rows, []string{fmt.Sprintf("%06d", status.Version), status.Name, appliedAt})
+			}
+			return printResult(*format, statuses, []string{"VERSION", "NAME", "APPLIED AT"}, rows)
+		}
+		return nil
+	}
+	return runCommand(cfg, command, &migrator)
+}
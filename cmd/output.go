@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// outputFormat is table or json, checked when the flag is parsed so a typo
+// fails before anything runs.
+type outputFormat string
+
+func (format *outputFormat) String() string {
+	return string(*format)
+}
+
+func (format *outputFormat) Set(value string) error {
+	if value != "table" && value != "json" {
+		return fmt.Errorf("unknown output format %q, expected table or json", value)
+	}
+	*format = outputFormat(value)
+	return nil
+}
+
+// outputFlag adds the -output flag of the commands printing results.
+func outputFlag(flags *flag.FlagSet) *outputFormat {
+	format := outputFormat("table")
+	flags.Var(&format, "output", "output format, table or json")
+	return &format
+}
+
+// printResult prints value as indented JSON, or as the table built by rows
+// under header.
+func printResult(format outputFormat, value interface{}, header []string, rows [][]string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	return printTable(header, rows)
+}
+
+func printTable(header []string, rows [][]string) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatID(id *uint) string {
+	if id == nil {
+		return "-"
+	}
+	return fmt.Sprint(*id)
+}
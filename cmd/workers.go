@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Workers runs the background loops and stops them one after the other, in
+// the order they were started, so a worker is stopped before the ones
+// handling what it produces.
+type Workers struct {
+	workers []*worker
+}
+
+func (workers *Workers) Go(name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{name: name, cancel: cancel, done: make(chan struct{})}
+	workers.workers = append(workers.workers, w)
+
+	go func() {
+		defer close(w.done)
+		run(ctx)
+	}()
+}
+
+// Stop cancels each worker and waits for it to finish its current run before
+// stopping the next one. Workers still running when ctx ends are cancelled
+// without waiting.
+func (workers *Workers) Stop(ctx context.Context) error {
+	for i, w := range workers.workers {
+		w.cancel()
+		select {
+		case <-w.done:
+			zap.L().Info("worker stopped", zap.String("worker", w.name))
+		case <-ctx.Done():
+			for _, remaining := range workers.workers[i+1:] {
+				remaining.cancel()
+			}
+			zap.L().Error("error stopping worker", zap.String("worker", w.name), zap.Error(ctx.Err()))
+			return ctx.Err()
+		}
+	}
+	return nil
+}
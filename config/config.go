@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rental_service/auth"
+	"rental_service/notification"
+	"rental_service/rent"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the whole service. It is read from the
+// YAML or TOML file named by CONFIG_FILE, if any, then from the environment
+// variables named in the env tags, which take precedence. Fields tagged
+// secret are redacted by Redacted.
+type Config struct {
+	Server       ServerConfig        `yaml:"server" toml:"server"`
+	Database     DatabaseConfig      `yaml:"database" toml:"database"`
+	Services     rent.Config         `yaml:"services" toml:"services"`
+	Auth         auth.Config         `yaml:"auth" toml:"auth"`
+	Notification notification.Config `yaml:"notification" toml:"notification"`
+	Events       EventsConfig        `yaml:"events" toml:"events"`
+}
+
+type ServerConfig struct {
+	Address     string   `yaml:"address" toml:"address" env:"SERVER_ADDRESS" validate:"required"`
+	CORSOrigins []string `yaml:"corsOrigins" toml:"corsOrigins" env:"CORS_ORIGINS"`
+	TLSCertFile string   `yaml:"tlsCertFile" toml:"tlsCertFile" env:"TLS_CERT_FILE" validate:"required_with=TLSKeyFile"`
+	TLSKeyFile  string   `yaml:"tlsKeyFile" toml:"tlsKeyFile" env:"TLS_KEY_FILE" validate:"required_with=TLSCertFile"`
+	// ShutdownTimeout bounds the drain of in-flight requests on shutdown;
+	// the background workers get as long again to stop.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" toml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" validate:"min=1s"`
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host" toml:"host" env:"DB_HOST" validate:"required"`
+	Port            int           `yaml:"port" toml:"port" env:"DB_PORT" validate:"min=1,max=65535"`
+	User            string        `yaml:"user" toml:"user" env:"DB_USER" validate:"required"`
+	Password        string        `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name            string        `yaml:"name" toml:"name" env:"DB_NAME" validate:"required"`
+	SearchPath      string        `yaml:"searchPath" toml:"searchPath" env:"DB_SEARCH_PATH"`
+	SSLMode         string        `yaml:"sslMode" toml:"sslMode" env:"DB_SSL_MODE" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	MaxOpenConns    int           `yaml:"maxOpenConns" toml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS" validate:"min=0"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" toml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS" validate:"min=0"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" toml:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME" validate:"min=0"`
+	// AutoMigrate applies the pending migrations when the server starts.
+	AutoMigrate bool `yaml:"autoMigrate" toml:"autoMigrate" env:"DB_AUTO_MIGRATE"`
+}
+
+// DSN is the connection string of the database. It holds the password, so
+// it must not be logged.
+func (database DatabaseConfig) DSN() string {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s", quoteDSN(database.Host), quoteDSN(database.User), quoteDSN(database.Password), quoteDSN(database.Name), database.Port, quoteDSN(database.SSLMode))
+	if database.SearchPath != "" {
+		dsn += " search_path=" + quoteDSN(database.SearchPath)
+	}
+	return dsn
+}
+
+// quoteDSN quotes a connection string value, which may be empty or contain
+// spaces and quotes.
+func quoteDSN(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
+
+// EventsConfig tunes the outbox relay. WebhookURL additionally posts every
+// event to a single URL, e.g. a message broker bridge.
+type EventsConfig struct {
+	RelayInterval time.Duration `yaml:"relayInterval" toml:"relayInterval" env:"EVENTS_RELAY_INTERVAL" validate:"min=1s"`
+	WebhookURL    string        `yaml:"webhookUrl" toml:"webhookUrl" env:"EVENTS_WEBHOOK_URL" validate:"omitempty,url"`
+}
+
+// Default is the configuration of a local development setup.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:         ":8082",
+			CORSOrigins:     []string{"http://localhost:8083"},
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "admin",
+			Name:            "rental_service_db",
+			SearchPath:      "rent-request-service",
+			SSLMode:         "disable",
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+		},
+		Services: rent.Config{
+			PostServiceURL:    "http://localhost:8081",
+			UserServiceURL:    "http://localhost:8080",
+			PaymentServiceURL: "http://localhost:8083",
+			CallbackBaseURL:   "http://localhost:8082",
+			Timeout:           10 * time.Second,
+		},
+		Auth: auth.Config{
+			JWKSRefresh:     15 * time.Minute,
+			ClockSkew:       30 * time.Second,
+			ServiceAudience: "rental-service-internal",
+		},
+		Notification: notification.Config{
+			Email: notification.EmailConfig{Channel: "console", SMTPPort: 587},
+			SMS:   notification.SMSConfig{Channel: "console"},
+		},
+		Events: EventsConfig{
+			RelayInterval: 5 * time.Second,
+		},
+	}
+}
+
+// Load reads the configuration from the file named by CONFIG_FILE and the
+// environment.
+func Load() (*Config, error) {
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile is Load with the given file, which may be empty. The Auth section
+// is left to ValidateAuth: only the server authenticates anyone, and the
+// maintenance commands must run without it.
+func LoadFile(path string) (*Config, error) {
+	config := Default()
+
+	if path != "" {
+		err := decodeFile(path, &config)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+		}
+	}
+
+	err := applyEnv(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validator.New().StructExcept(config, "Auth")
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &config, nil
+}
+
+// ValidateAuth validates the whole configuration, the Auth section included,
+// which the server needs.
+func (config *Config) ValidateAuth() error {
+	err := validator.New().Struct(config)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// decodeFile decodes the file by its extension. Unknown keys are errors, so
+// that typos do not silently fall back to the defaults.
+func decodeFile(path string, config *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		err = decoder.Decode(config)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	case ".toml":
+		metadata, err := toml.NewDecoder(file).Decode(config)
+		if err != nil {
+			return err
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys %v", undecoded)
+		}
+		return nil
+	}
+	return errors.New("unsupported config file type, use .yaml, .yml or .toml")
+}
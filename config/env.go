@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv sets the fields having an env tag from the environment variables
+// which are set. Lists are comma separated and durations use Go syntax, e.g.
+// "30s".
+func applyEnv(config *Config) error {
+	return applyEnvToStruct(reflect.ValueOf(config).Elem())
+}
+
+func applyEnvToStruct(value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+
+		name, ok := structField.Tag.Lookup("env")
+		if !ok {
+			if field.Kind() == reflect.Struct {
+				if err := applyEnvToStruct(field); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, env); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, env string) error {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(env)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(env)
+	case reflect.Int:
+		number, err := strconv.Atoi(env)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(number))
+	case reflect.Bool:
+		boolean, err := strconv.ParseBool(env)
+		if err != nil {
+			return err
+		}
+		field.SetBool(boolean)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(env, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import "reflect"
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the configuration safe to log, with the secrets
+// that are set replaced by a placeholder.
+func (config Config) Redacted() Config {
+	redactStruct(reflect.ValueOf(&config).Elem())
+	return config
+}
+
+func redactStruct(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if value.Type().Field(i).Tag.Get("secret") == "true" {
+			if field.Kind() == reflect.String && field.String() != "" {
+				field.SetString(redacted)
+			}
+			continue
+		}
+		if field.Kind() == reflect.Struct {
+			redactStruct(field)
+		}
+	}
+}
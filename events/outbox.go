@@ -0,0 +1,91 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RentRequestCreated   = "RentRequestCreated"
+	RentRequestConfirmed = "RentRequestConfirmed"
+	RentRequestPaid      = "RentRequestPaid"
+	RentRequestRejected  = "RentRequestRejected"
+	RentRequestCancelled = "RentRequestCancelled"
+	RentRequestCompleted = "RentRequestCompleted"
+	RentRequestModified  = "RentRequestModified"
+)
+
+// OutboxEvent is a domain event waiting in the outbox table to be published.
+// It is written in the same transaction as the change it describes so no
+// event is lost or published for a change that was rolled back.
+type OutboxEvent struct {
+	ID          uint            `json:"id"`
+	EventType   string          `json:"type"`
+	AggregateID uint            `json:"aggregateId"`
+	Payload     json.RawMessage `json:"payload" gorm:"type:jsonb"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	PublishedAt *time.Time      `json:"-"`
+	Attempts    int             `json:"-"`
+	LastError   string          `json:"-"`
+	DeadAt      *time.Time      `json:"-"`
+	LockedUntil *time.Time      `json:"-"`
+}
+
+// Enqueue adds an event to the outbox using the given transaction.
+func Enqueue(tx *gorm.DB, eventType string, aggregateId uint, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event := &OutboxEvent{
+		EventType:   eventType,
+		AggregateID: aggregateId,
+		Payload:     body,
+		CreatedAt:   time.Now(),
+	}
+	return tx.Create(&event).Error
+}
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimPendingEvents leases the oldest pending events for lease. Other relays
+// skip them meanwhile, so several can run side by side without publishing an
+// event twice, and no row stays locked while the events are published.
+func (outboxRepo *OutboxRepository) ClaimPendingEvents(limit int, lease time.Duration) ([]OutboxEvent, error) {
+	var pendingEvents []OutboxEvent
+	err := outboxRepo.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND dead_at IS NULL AND (locked_until IS NULL OR locked_until < ?)", now).
+			Order("id").
+			Limit(limit).
+			Find(&pendingEvents).Error
+		if err != nil || len(pendingEvents) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(pendingEvents))
+		for i := range pendingEvents {
+			ids[i] = pendingEvents[i].ID
+		}
+		return tx.Model(&OutboxEvent{}).Where("id IN ?", ids).Update("locked_until", now.Add(lease)).Error
+	})
+	return pendingEvents, err
+}
+
+// ReleaseEvent saves the outcome of publishing a claimed event, like the
+// publishing time or the last error, and ends its lease.
+func (outboxRepo *OutboxRepository) ReleaseEvent(event *OutboxEvent) error {
+	event.LockedUntil = nil
+	return outboxRepo.db.Save(event).Error
+}
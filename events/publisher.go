@@ -0,0 +1,137 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Publisher delivers outbox events to the other services. Publish may be
+// called again for an event that was already delivered if the relay stops
+// before recording it, so consumers should deduplicate on the event ID.
+type Publisher interface {
+	Publish(ctx context.Context, event *OutboxEvent) error
+}
+
+type LogPublisher struct{}
+
+func NewLogPublisher() Publisher {
+	return &LogPublisher{}
+}
+
+func (publisher *LogPublisher) Publish(ctx context.Context, event *OutboxEvent) error {
+	zap.L().Info("domain event",
+		zap.Uint("eventId", event.ID),
+		zap.String("type", event.EventType),
+		zap.Uint("aggregateId", event.AggregateID),
+		zap.ByteString("payload", event.Payload),
+	)
+	return nil
+}
+
+// MultiPublisher publishes every event to each of its publishers in turn.
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+func NewMultiPublisher(publishers ...Publisher) *MultiPublisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+func (publisher *MultiPublisher) Publish(ctx context.Context, event *OutboxEvent) error {
+	for _, next := range publisher.publishers {
+		err := next.Publish(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookPublisher posts every event as JSON to a single URL.
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookPublisher(url string) *WebhookPublisher {
+	return &WebhookPublisher{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (publisher *WebhookPublisher) Publish(ctx context.Context, event *OutboxEvent) error {
+	requestBody, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", publisher.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Event-Id", fmt.Sprint(event.ID))
+	request.Header.Set("X-Event-Type", event.EventType)
+
+	response, err := publisher.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("an error occurred: status code %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+// MemoryBroker keeps published events in memory and passes them on to its
+// subscribers. It is meant for tests and local runs.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	published   []OutboxEvent
+	subscribers []chan OutboxEvent
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+// Subscribe returns a channel receiving the events published from now on.
+// Events are dropped for subscribers that fall more than buffer events behind.
+func (broker *MemoryBroker) Subscribe(buffer int) <-chan OutboxEvent {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	subscriber := make(chan OutboxEvent, buffer)
+	broker.subscribers = append(broker.subscribers, subscriber)
+	return subscriber
+}
+
+func (broker *MemoryBroker) Publish(ctx context.Context, event *OutboxEvent) error {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	broker.published = append(broker.published, *event)
+	for _, subscriber := range broker.subscribers {
+		select {
+		case subscriber <- *event:
+		default:
+		}
+	}
+	return nil
+}
+
+// Published returns every event published so far, oldest first.
+func (broker *MemoryBroker) Published() []OutboxEvent {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	return append([]OutboxEvent{}, broker.published...)
+}
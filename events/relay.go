@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	relayBatchSize = 100
+	// publishTimeout bounds each publish so a slow publisher cannot hold the
+	// batch past its lease.
+	publishTimeout = 10 * time.Second
+	// maxAttempts is how many times an event is tried before it is set aside
+	// as dead.
+	maxAttempts = 10
+)
+
+// Relay publishes the events of the outbox in the order they were written.
+type Relay struct {
+	repo      *OutboxRepository
+	publisher Publisher
+}
+
+func NewRelay(repo *OutboxRepository, publisher Publisher) *Relay {
+	return &Relay{repo: repo, publisher: publisher}
+}
+
+// PublishPending publishes a batch of pending events. Order only matters for
+// the events of one rent request: when an event cannot be published the
+// later events of its rent request wait for the next run, while the others
+// go on. An event failing maxAttempts times is marked dead and stops holding
+// its rent request back.
+func (relay *Relay) PublishPending(ctx context.Context) error {
+	pendingEvents, err := relay.repo.ClaimPendingEvents(relayBatchSize, relayBatchSize*publishTimeout)
+	if err != nil {
+		return err
+	}
+
+	blocked := map[uint]bool{}
+	for i := range pendingEvents {
+		event := &pendingEvents[i]
+		if !blocked[event.AggregateID] && ctx.Err() == nil {
+			relay.publish(ctx, event)
+			if event.PublishedAt == nil && event.DeadAt == nil {
+				blocked[event.AggregateID] = true
+			}
+		}
+
+		err = relay.repo.ReleaseEvent(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (relay *Relay) publish(ctx context.Context, event *OutboxEvent) {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	err := relay.publisher.Publish(ctx, event)
+	event.Attempts++
+	now := time.Now()
+	if err == nil {
+		event.LastError = ""
+		event.PublishedAt = &now
+		return
+	}
+
+	event.LastError = err.Error()
+	if event.Attempts >= maxAttempts {
+		event.DeadAt = &now
+		zap.L().Error("giving up publishing event", zap.Uint("eventId", event.ID), zap.String("type", event.EventType), zap.Int("attempts", event.Attempts), zap.Error(err))
+		return
+	}
+	zap.L().Error("error publishing event", zap.Uint("eventId", event.ID), zap.String("type", event.EventType), zap.Error(err))
+}
+
+// Run publishes pending events periodically until ctx is done.
+func (relay *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := relay.PublishPending(ctx); err != nil {
+				zap.L().Error("error relaying outbox events", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rental_service"
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of the HTTP requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	rentRequestEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rent_request_events_total",
+		Help:      "Rent requests created, confirmed, paid, cancelled, rejected, completed and modified.",
+	}, []string{"event"})
+
+	dependencyRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dependency_request_duration_seconds",
+		Help:      "Duration of the calls to the services we depend on, by outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"dependency", "operation", "outcome"})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.Handler())
+}
+
+// Middleware times the requests by route template, so IDs in the paths do not
+// each get their own series.
+func Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		// The error handler only writes the response after the middlewares.
+		status := c.Response().Status
+		if err != nil {
+			status = http.StatusInternalServerError
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			}
+		}
+
+		// Unknown paths would each get their own series otherwise.
+		route := c.Path()
+		if route == "" || (route == "/*" && status == http.StatusNotFound) {
+			route = "unmatched"
+		}
+		httpRequestDuration.WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// CountRentRequestEvent counts a committed change of a rent request, e.g.
+// RentRequestPaid as "paid".
+func CountRentRequestEvent(eventType string) {
+	rentRequestEvents.WithLabelValues(strings.ToLower(strings.TrimPrefix(eventType, "RentRequest"))).Inc()
+}
+
+// ObserveDependency records a call to a service we depend on, started at
+// start, once it returned *err. It is meant to be deferred:
+//
+//	defer metrics.ObserveDependency("post_service", "get_post", time.Now(), &err)
+func ObserveDependency(dependency, operation string, start time.Time, err *error) {
+	outcome := "success"
+	if *err != nil {
+		outcome = "error"
+	}
+	dependencyRequestDuration.WithLabelValues(dependency, operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDB exposes the connection pool statistics of the database as
+// gauges.
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
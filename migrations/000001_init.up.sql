@@ -9,5 +9,5 @@ CREATE TABLE rent_requests (
     status VARCHAR(50) NOT NULL,
     payment_status VARCHAR(50) NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
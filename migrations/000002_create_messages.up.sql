@@ -0,0 +1,10 @@
+CREATE TABLE messages (
+    id SERIAL PRIMARY KEY,
+    rent_request_id INTEGER NOT NULL REFERENCES rent_requests (id) ON DELETE CASCADE,
+    sender_id INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_messages_rent_request_id ON messages (rent_request_id, created_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS rent_offers;
\ No newline at end of file
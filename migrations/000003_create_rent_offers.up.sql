@@ -0,0 +1,13 @@
+CREATE TABLE rent_offers (
+    id SERIAL PRIMARY KEY,
+    rent_request_id INTEGER NOT NULL REFERENCES rent_requests (id) ON DELETE CASCADE,
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP NOT NULL,
+    total_price INT NOT NULL,
+    price_proposed BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_rent_offers_rent_request_id ON rent_offers (rent_request_id);
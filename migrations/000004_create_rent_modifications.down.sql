@@ -0,0 +1 @@
+DROP TABLE IF EXISTS rent_modifications;
\ No newline at end of file
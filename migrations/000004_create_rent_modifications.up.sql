@@ -0,0 +1,14 @@
+CREATE TABLE rent_modifications (
+    id SERIAL PRIMARY KEY,
+    rent_request_id INTEGER NOT NULL REFERENCES rent_requests (id) ON DELETE CASCADE,
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP NOT NULL,
+    total_price INT NOT NULL,
+    price_difference INT NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    payment_status VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_rent_modifications_rent_request_id ON rent_modifications (rent_request_id);
@@ -0,0 +1 @@
+ALTER TABLE rent_requests DROP COLUMN IF EXISTS parent_request_id;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE rent_requests ADD COLUMN parent_request_id INTEGER REFERENCES rent_requests (id);
+
+CREATE INDEX idx_rent_requests_parent_request_id ON rent_requests (parent_request_id);
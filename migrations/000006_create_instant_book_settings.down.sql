@@ -0,0 +1 @@
+DROP TABLE IF EXISTS instant_book_settings;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE instant_book_settings (
+    post_id INTEGER PRIMARY KEY,
+    owner_id INTEGER NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    require_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    min_rating NUMERIC(3, 2) NOT NULL DEFAULT 0,
+    no_past_cancellations BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
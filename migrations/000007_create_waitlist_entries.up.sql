@@ -0,0 +1,15 @@
+CREATE TABLE waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    renter_id INTEGER NOT NULL,
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    notified_at TIMESTAMP,
+    priority_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_waitlist_entries_post_id ON waitlist_entries (post_id, status);
+CREATE INDEX idx_waitlist_entries_renter_id ON waitlist_entries (renter_id);
@@ -0,0 +1,15 @@
+ALTER TABLE waitlist_entries
+    ALTER COLUMN start_date TYPE TIMESTAMP USING start_date AT TIME ZONE 'UTC',
+    ALTER COLUMN end_date TYPE TIMESTAMP USING end_date AT TIME ZONE 'UTC';
+
+ALTER TABLE rent_modifications
+    ALTER COLUMN start_date TYPE TIMESTAMP USING start_date AT TIME ZONE 'UTC',
+    ALTER COLUMN end_date TYPE TIMESTAMP USING end_date AT TIME ZONE 'UTC';
+
+ALTER TABLE rent_offers
+    ALTER COLUMN start_date TYPE TIMESTAMP USING start_date AT TIME ZONE 'UTC',
+    ALTER COLUMN end_date TYPE TIMESTAMP USING end_date AT TIME ZONE 'UTC';
+
+ALTER TABLE rent_requests
+    ALTER COLUMN start_date TYPE TIMESTAMP USING start_date AT TIME ZONE 'UTC',
+    ALTER COLUMN end_date TYPE TIMESTAMP USING end_date AT TIME ZONE 'UTC';
\ No newline at end of file
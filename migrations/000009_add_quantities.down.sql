@@ -0,0 +1,3 @@
+ALTER TABLE waitlist_entries DROP COLUMN IF EXISTS quantity;
+
+ALTER TABLE rent_requests DROP COLUMN IF EXISTS quantity;
\ No newline at end of file
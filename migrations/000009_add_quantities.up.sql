@@ -0,0 +1,3 @@
+ALTER TABLE rent_requests ADD COLUMN quantity INT NOT NULL DEFAULT 1;
+
+ALTER TABLE waitlist_entries ADD COLUMN quantity INT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE rent_requests DROP COLUMN IF EXISTS booking_group_id;
+
+DROP TABLE IF EXISTS booking_groups;
@@ -0,0 +1,15 @@
+CREATE TABLE booking_groups (
+    id SERIAL PRIMARY KEY,
+    renter_id INTEGER NOT NULL,
+    total_price INTEGER NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    payment_status VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_booking_groups_renter_id ON booking_groups (renter_id);
+
+ALTER TABLE rent_requests ADD COLUMN booking_group_id INTEGER REFERENCES booking_groups (id);
+
+CREATE INDEX idx_rent_requests_booking_group_id ON rent_requests (booking_group_id);
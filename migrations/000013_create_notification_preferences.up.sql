@@ -0,0 +1,8 @@
+CREATE TABLE notification_preferences (
+    user_id INTEGER PRIMARY KEY,
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    sms_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    locale VARCHAR(20) NOT NULL DEFAULT '',
+    disabled_types TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,13 @@
+CREATE TABLE admin_actions (
+    id SERIAL PRIMARY KEY,
+    rent_request_id INTEGER NOT NULL REFERENCES rent_requests(id),
+    admin_id INTEGER NOT NULL,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    from_payment_status VARCHAR(50) NOT NULL DEFAULT '',
+    to_payment_status VARCHAR(50) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_admin_actions_rent_request_id ON admin_actions (rent_request_id);
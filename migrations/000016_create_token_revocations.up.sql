@@ -0,0 +1,18 @@
+CREATE TABLE revoked_tokens (
+    token_id VARCHAR(255) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_by INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE user_revocations (
+    user_id INTEGER PRIMARY KEY,
+    revoked_before TIMESTAMP NOT NULL,
+    revoked_by INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_outbox_events_pending;
+
+ALTER TABLE outbox_events
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS dead_at;
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events (id) WHERE published_at IS NULL;
@@ -0,0 +1,9 @@
+-- Events failing too many times are set aside as dead instead of holding
+-- back the outbox, and claimed events are leased rather than kept locked
+-- while they are published.
+ALTER TABLE outbox_events
+    ADD COLUMN dead_at TIMESTAMP,
+    ADD COLUMN locked_until TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_outbox_events_pending;
+CREATE INDEX idx_outbox_events_pending ON outbox_events (id) WHERE published_at IS NULL AND dead_at IS NULL;
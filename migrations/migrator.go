@@ -0,0 +1,312 @@
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock taken by every migration step so
+// replicas migrating on startup do not race.
+const lockKey = 72635010
+
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// Migration is a pair of NNNNNN_name.up.sql and NNNNNN_name.down.sql scripts.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+type MigrationStatus struct {
+	Version   uint       `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, fileName := range names {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		versionStr, name, found := strings.Cut(base, "_")
+		if !ok || !found || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+		version, err := strconv.ParseUint(versionStr, 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", fileName)
+		}
+
+		body, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[uint(version)]
+		if migration == nil {
+			migration = &Migration{Version: uint(version), Name: name}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has two names, %q and %q", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%s needs non-empty up and down scripts", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Latest is the version of the last known migration.
+func (migrator *Migrator) Latest() uint {
+	if len(migrator.migrations) == 0 {
+		return 0
+	}
+	return migrator.migrations[len(migrator.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (migrator *Migrator) Up() error {
+	return migrator.Goto(migrator.Latest())
+}
+
+// Down rolls back the last steps applied migrations.
+func (migrator *Migrator) Down(steps int) error {
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	index := migrator.index(version)
+	if index < 0 && version != 0 {
+		return fmt.Errorf("%w: %d is applied but not known", ErrUnknownVersion, version)
+	}
+	target := index - steps
+	if target < 0 {
+		return migrator.Goto(0)
+	}
+	return migrator.Goto(migrator.migrations[target].Version)
+}
+
+// Goto applies or rolls back migrations until version is the last applied
+// one; 0 rolls back everything. Each migration runs in its own transaction
+// holding the advisory lock, so another replica either waits for it or finds
+// it already done.
+func (migrator *Migrator) Goto(version uint) error {
+	if version != 0 && migrator.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	for {
+		done := false
+		err := migrator.db.Transaction(func(tx *gorm.DB) error {
+			err := lock(tx)
+			if err != nil {
+				return err
+			}
+
+			current, err := currentVersion(tx)
+			if err != nil {
+				return err
+			}
+			if current == version {
+				done = true
+				return nil
+			}
+
+			index := migrator.index(current)
+			if index < 0 && current != 0 {
+				return fmt.Errorf("%w: %d is applied but not known", ErrUnknownVersion, current)
+			}
+
+			if current < version {
+				migration := migrator.migrations[index+1]
+				err = tx.Exec(migration.Up).Error
+				if err != nil {
+					return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+				}
+				zap.L().Info("applied migration", zap.Uint("version", migration.Version), zap.String("name", migration.Name))
+				return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			}
+
+			migration := migrator.migrations[index]
+			err = tx.Exec(migration.Down).Error
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			zap.L().Info("rolled back migration", zap.Uint("version", migration.Version), zap.String("name", migration.Name))
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// Force records the migrations up to version as applied and the later ones
+// as pending, without running any script. It is meant to baseline a database
+// whose schema was created before the migrations, or to recover from a
+// migration that failed halfway once the schema has been fixed by hand.
+func (migrator *Migrator) Force(version uint) error {
+	if version != 0 && migrator.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return migrator.db.Transaction(func(tx *gorm.DB) error {
+		err := lock(tx)
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("DELETE FROM schema_migrations WHERE version > ?", version).Error
+		if err != nil {
+			return err
+		}
+
+		var applied []uint
+		err = tx.Model(&SchemaMigration{}).Pluck("version", &applied).Error
+		if err != nil {
+			return err
+		}
+		isApplied := map[uint]bool{}
+		for _, appliedVersion := range applied {
+			isApplied[appliedVersion] = true
+		}
+
+		for _, migration := range migrator.migrations {
+			if migration.Version > version || isApplied[migration.Version] {
+				continue
+			}
+			err = tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			if err != nil {
+				return err
+			}
+		}
+		zap.L().Info("forced migration version", zap.Uint("version", version))
+		return nil
+	})
+}
+
+// Version is the last applied migration, 0 when none is.
+func (migrator *Migrator) Version() (uint, error) {
+	var version uint
+	err := migrator.db.Transaction(func(tx *gorm.DB) error {
+		err := lock(tx)
+		if err != nil {
+			return err
+		}
+		version, err = currentVersion(tx)
+		return err
+	})
+	return version, err
+}
+
+// Status lists the known migrations and whether they are applied.
+func (migrator *Migrator) Status() ([]MigrationStatus, error) {
+	var applied []SchemaMigration
+	err := migrator.db.Transaction(func(tx *gorm.DB) error {
+		err := lock(tx)
+		if err != nil {
+			return err
+		}
+		return tx.Order("version").Find(&applied).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := map[uint]time.Time{}
+	for _, schemaMigration := range applied {
+		appliedAt[schemaMigration.Version] = schemaMigration.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrator.migrations))
+	for _, migration := range migrator.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (migrator *Migrator) index(version uint) int {
+	for i, migration := range migrator.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// lock takes the migration lock until the end of the transaction and creates
+// the version table on first use.
+func lock(tx *gorm.DB) error {
+	err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error
+	if err != nil {
+		return err
+	}
+	return tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`).Error
+}
+
+func currentVersion(tx *gorm.DB) (uint, error) {
+	var version uint
+	err := tx.Raw("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version).Error
+	return version, err
+}
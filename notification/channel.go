@@ -0,0 +1,204 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Channel sends rendered messages to an email address or a phone number.
+type Channel interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Channels are the channels notifications are sent through. A nil channel
+// disables it.
+type Channels struct {
+	Email Channel
+	SMS   Channel
+}
+
+// smtpTimeout bounds a whole SMTP exchange when the context has no earlier
+// deadline, so a hung server cannot hold up the outbox relay.
+const smtpTimeout = 30 * time.Second
+
+// headerReplacer strips the line breaks a user-controlled value, like a post
+// title in the subject, could use to add headers or start the body.
+var headerReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+type SMTPChannel struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPChannel(host string, port int, username, password, from string) *SMTPChannel {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPChannel{host: host, addr: fmt.Sprintf("%s:%d", host, port), auth: auth, from: from}
+}
+
+// Send does what smtp.SendMail does, but gives up when ctx is done or after
+// smtpTimeout.
+func (channel *SMTPChannel) Send(ctx context.Context, message Message) error {
+	to := headerReplacer.Replace(message.To)
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", headerReplacer.Replace(channel.from))
+	fmt.Fprintf(&body, "To: %s\r\n", to)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerReplacer.Replace(message.Subject)))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(message.Body)
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", channel.addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, channel.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: channel.host})
+		if err != nil {
+			return err
+		}
+	}
+	if channel.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("the SMTP server does not support AUTH")
+		}
+		err = client.Auth(channel.auth)
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(channel.from)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(to)
+	if err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write([]byte(body.String()))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// SMSChannel sends text messages through an HTTP SMS provider accepting
+// {"from", "to", "text"} with a bearer API key.
+type SMSChannel struct {
+	url    string
+	apiKey string
+	from   string
+	client *http.Client
+}
+
+func NewSMSChannel(url, apiKey, from string) *SMSChannel {
+	return &SMSChannel{url: url, apiKey: apiKey, from: from, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (channel *SMSChannel) Send(ctx context.Context, message Message) error {
+	requestBody, err := json.Marshal(map[string]string{
+		"from": channel.from,
+		"to":   message.To,
+		"text": message.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", channel.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+channel.apiKey)
+
+	response, err := channel.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("an error occurred: status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// SinkChannel writes messages to a file or the console instead of sending
+// them, for local development.
+type SinkChannel struct {
+	mu     sync.Mutex
+	name   string
+	writer io.Writer
+}
+
+func NewConsoleChannel(name string) *SinkChannel {
+	return &SinkChannel{name: name, writer: os.Stdout}
+}
+
+func NewFileChannel(name, path string) (*SinkChannel, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &SinkChannel{name: name, writer: file}, nil
+}
+
+func (channel *SinkChannel) Send(ctx context.Context, message Message) error {
+	channel.mu.Lock()
+	defer channel.mu.Unlock()
+
+	_, err := fmt.Fprintf(channel.writer, "--- %s to %s at %s\nSubject: %s\n\n%s\n\n", channel.name, message.To, time.Now().Format(time.RFC3339), message.Subject, message.Body)
+	return err
+}
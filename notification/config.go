@@ -0,0 +1,57 @@
+package notification
+
+// Config chooses how each channel sends: "smtp" or "http" for the real
+// providers, "console" or "file" (appending to FilePath) for development, or
+// "none" to disable the channel.
+type Config struct {
+	Email EmailConfig `yaml:"email" toml:"email"`
+	SMS   SMSConfig   `yaml:"sms" toml:"sms"`
+}
+
+type EmailConfig struct {
+	Channel      string `yaml:"channel" toml:"channel" env:"EMAIL_CHANNEL" validate:"oneof=smtp console file none"`
+	FilePath     string `yaml:"filePath" toml:"filePath" env:"EMAIL_FILE_PATH" validate:"required_if=Channel file"`
+	SMTPHost     string `yaml:"smtpHost" toml:"smtpHost" env:"SMTP_HOST" validate:"required_if=Channel smtp"`
+	SMTPPort     int    `yaml:"smtpPort" toml:"smtpPort" env:"SMTP_PORT" validate:"min=0,max=65535"`
+	SMTPUsername string `yaml:"smtpUsername" toml:"smtpUsername" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtpPassword" toml:"smtpPassword" env:"SMTP_PASSWORD" secret:"true"`
+	From         string `yaml:"from" toml:"from" env:"EMAIL_FROM" validate:"required_if=Channel smtp"`
+}
+
+type SMSConfig struct {
+	Channel  string `yaml:"channel" toml:"channel" env:"SMS_CHANNEL" validate:"oneof=http console file none"`
+	FilePath string `yaml:"filePath" toml:"filePath" env:"SMS_FILE_PATH" validate:"required_if=Channel file"`
+	URL      string `yaml:"url" toml:"url" env:"SMS_URL" validate:"required_if=Channel http,omitempty,url"`
+	APIKey   string `yaml:"apiKey" toml:"apiKey" env:"SMS_API_KEY" secret:"true"`
+	From     string `yaml:"from" toml:"from" env:"SMS_FROM"`
+}
+
+func NewChannels(config Config) (Channels, error) {
+	var channels Channels
+	var err error
+
+	switch config.Email.Channel {
+	case "smtp":
+		channels.Email = NewSMTPChannel(config.Email.SMTPHost, config.Email.SMTPPort, config.Email.SMTPUsername, config.Email.SMTPPassword, config.Email.From)
+	case "console":
+		channels.Email = NewConsoleChannel("email")
+	case "file":
+		channels.Email, err = NewFileChannel("email", config.Email.FilePath)
+	}
+	if err != nil {
+		return Channels{}, err
+	}
+
+	switch config.SMS.Channel {
+	case "http":
+		channels.SMS = NewSMSChannel(config.SMS.URL, config.SMS.APIKey, config.SMS.From)
+	case "console":
+		channels.SMS = NewConsoleChannel("sms")
+	case "file":
+		channels.SMS, err = NewFileChannel("sms", config.SMS.FilePath)
+	}
+	if err != nil {
+		return Channels{}, err
+	}
+	return channels, nil
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type NotificationHandler struct {
+	service *NotificationService
+
+	validate *validator.Validate
+}
+
+func NewNotificationHandler(service *NotificationService, validate *validator.Validate) *NotificationHandler {
+	return &NotificationHandler{service: service, validate: validate}
+}
+
+type PreferenceDto struct {
+	EmailEnabled  bool     `json:"emailEnabled"`
+	SMSEnabled    bool     `json:"smsEnabled"`
+	Locale        string   `json:"locale" validate:"omitempty,max=20"`
+	DisabledTypes []string `json:"disabledTypes" validate:"dive,oneof=new_request instant_booking request_confirmed request_paid payment_received request_rejected request_cancelled booking_completed waitlist_spot"`
+}
+
+func (handler *NotificationHandler) GetPreference(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	preference, err := handler.service.GetPreference(userId)
+	if err != nil {
+		zap.L().Error("error getting notification preference", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch notification preference")
+	}
+
+	return c.JSON(http.StatusOK, preference)
+}
+
+func (handler *NotificationHandler) UpdatePreference(c echo.Context) error {
+	var preference PreferenceDto
+
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err := c.Bind(&preference); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(preference); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	updatedPreference, err := handler.service.UpdatePreference(userId, preference)
+	if err != nil {
+		zap.L().Error("error updating notification preference", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update notification preference")
+	}
+
+	return c.JSON(http.StatusOK, updatedPreference)
+}
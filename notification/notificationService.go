@@ -0,0 +1,233 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"rental_service/events"
+	"rental_service/rent"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const dateLayout = "2006-01-02 15:04"
+
+type NotificationService struct {
+	repo          *PreferenceRepository
+	channels      Channels
+	serviceClient *rent.ServiceClient
+}
+
+func NewNotificationService(repo *PreferenceRepository, channels Channels, serviceClient *rent.ServiceClient) *NotificationService {
+	return &NotificationService{repo: repo, channels: channels, serviceClient: serviceClient}
+}
+
+type PreferenceResponse struct {
+	EmailEnabled  bool     `json:"email_enabled"`
+	SMSEnabled    bool     `json:"sms_enabled"`
+	Locale        string   `json:"locale"`
+	DisabledTypes []string `json:"disabled_types"`
+}
+
+type recipient struct {
+	userId uint
+	name   string
+}
+
+// Publish implements events.Publisher and notifies the renter and/or the
+// owner of the rent request the event is about. Failing notifications are
+// logged rather than returned: retrying the event would hold back the other
+// publishers and notify again the recipients already reached. Only a payload
+// that cannot be decoded is an error.
+func (service *NotificationService) Publish(ctx context.Context, event *events.OutboxEvent) error {
+	var rentRequestEvent rent.RentRequestEvent
+	err := json.Unmarshal(event.Payload, &rentRequestEvent)
+	if err != nil {
+		return err
+	}
+
+	var recipients []recipient
+	switch event.EventType {
+	case events.RentRequestCreated:
+		if rentRequestEvent.Status == "Confirmed" {
+			recipients = []recipient{{rentRequestEvent.OwnerID, "instant_booking"}, {rentRequestEvent.RenterID, "request_confirmed"}}
+		} else {
+			recipients = []recipient{{rentRequestEvent.OwnerID, "new_request"}}
+		}
+	case events.RentRequestConfirmed:
+		recipients = []recipient{{rentRequestEvent.RenterID, "request_confirmed"}}
+	case events.RentRequestPaid:
+		recipients = []recipient{{rentRequestEvent.OwnerID, "request_paid"}, {rentRequestEvent.RenterID, "payment_received"}}
+	case events.RentRequestRejected:
+		recipients = []recipient{{rentRequestEvent.RenterID, "request_rejected"}}
+	case events.RentRequestCancelled:
+		recipients = []recipient{{rentRequestEvent.OwnerID, "request_cancelled"}, {rentRequestEvent.RenterID, "request_cancelled"}}
+	case events.RentRequestCompleted:
+		recipients = []recipient{{rentRequestEvent.RenterID, "booking_completed"}}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	postDetail, err := service.serviceClient.GetPostByID(rentRequestEvent.PostID)
+	if err != nil {
+		zap.L().Error("error fetching post for notification", zap.Uint("eventId", event.ID), zap.Uint("postId", rentRequestEvent.PostID), zap.Error(err))
+		return nil
+	}
+
+	location := postLocation(postDetail)
+	data := TemplateData{
+		RentRequestID: rentRequestEvent.RentRequestID,
+		PostTitle:     postDetail.Title,
+		StartDate:     rentRequestEvent.StartDate.In(location).Format(dateLayout),
+		EndDate:       rentRequestEvent.EndDate.In(location).Format(dateLayout),
+		Quantity:      rentRequestEvent.Quantity,
+		TotalPrice:    rentRequestEvent.TotalPrice,
+	}
+
+	for _, recipient := range recipients {
+		err = service.notify(ctx, recipient.userId, recipient.name, data)
+		if err != nil {
+			zap.L().Error("error notifying user", zap.Uint("eventId", event.ID), zap.Uint("userId", recipient.userId), zap.String("notification", recipient.name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// NotifyWaitlistSpot implements rent.WaitlistNotifier.
+func (service *NotificationService) NotifyWaitlistSpot(entry *rent.WaitlistEntry) error {
+	postDetail, err := service.serviceClient.GetPostByID(entry.PostID)
+	if err != nil {
+		return err
+	}
+
+	location := postLocation(postDetail)
+	data := TemplateData{
+		PostTitle:     postDetail.Title,
+		StartDate:     entry.StartDate.In(location).Format(dateLayout),
+		EndDate:       entry.EndDate.In(location).Format(dateLayout),
+		Quantity:      entry.Quantity,
+		PriorityUntil: entry.PriorityUntil.In(location).Format(dateLayout),
+	}
+	return service.notify(context.Background(), entry.RenterID, "waitlist_spot", data)
+}
+
+func (service *NotificationService) GetPreference(userId uint) (*PreferenceResponse, error) {
+	preference, err := service.getPreference(userId)
+	if err != nil {
+		return nil, err
+	}
+	return newPreferenceResponse(preference), nil
+}
+
+func (service *NotificationService) UpdatePreference(userId uint, preferenceDto PreferenceDto) (*PreferenceResponse, error) {
+	preference := &NotificationPreference{
+		UserID:        userId,
+		EmailEnabled:  preferenceDto.EmailEnabled,
+		SMSEnabled:    preferenceDto.SMSEnabled,
+		Locale:        preferenceDto.Locale,
+		DisabledTypes: strings.Join(preferenceDto.DisabledTypes, ","),
+		UpdatedAt:     time.Now(),
+	}
+	err := service.repo.UpdatePreference(preference)
+	if err != nil {
+		return nil, err
+	}
+	return newPreferenceResponse(preference), nil
+}
+
+// notify sends a notification to the user through the channels they enabled.
+// A channel failing does not stop the others and is only logged, so an event
+// is not notified twice through the channels that worked.
+func (service *NotificationService) notify(ctx context.Context, userId uint, name string, data TemplateData) error {
+	preference, err := service.getPreference(userId)
+	if err != nil {
+		return err
+	}
+
+	for _, disabledType := range strings.Split(preference.DisabledTypes, ",") {
+		if disabledType == name {
+			return nil
+		}
+	}
+
+	user, err := service.serviceClient.GetUserByID(userId)
+	if err != nil {
+		return err
+	}
+
+	locale := preference.Locale
+	if locale == "" {
+		locale = user.Locale
+	}
+
+	if preference.EmailEnabled && service.channels.Email != nil && user.Email != "" {
+		message, err := renderMessage(locale, name, "body", user.Email, data)
+		if err != nil {
+			return err
+		}
+		if err := service.channels.Email.Send(ctx, *message); err != nil {
+			zap.L().Error("error sending email notification", zap.Uint("userId", userId), zap.String("notification", name), zap.Error(err))
+		}
+	}
+
+	if preference.SMSEnabled && service.channels.SMS != nil && user.Phone != "" {
+		message, err := renderMessage(locale, name, "sms", user.Phone, data)
+		if err != nil {
+			return err
+		}
+		if err := service.channels.SMS.Send(ctx, *message); err != nil {
+			zap.L().Error("error sending sms notification", zap.Uint("userId", userId), zap.String("notification", name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// getPreference returns the preference of the user, or the default one (email
+// only) when they never changed it.
+func (service *NotificationService) getPreference(userId uint) (*NotificationPreference, error) {
+	preference, err := service.repo.GetPreference(userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &NotificationPreference{UserID: userId, EmailEnabled: true}, nil
+	}
+	return preference, err
+}
+
+func renderMessage(locale, name, part, to string, data TemplateData) (*Message, error) {
+	subject, err := render(locale, name, "subject", data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := render(locale, name, part, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{To: to, Subject: subject, Body: body}, nil
+}
+
+// postLocation formats dates in the post's time zone, like the renter sent
+// them.
+func postLocation(postDetail *rent.PostResponseWithOwner) *time.Location {
+	location, err := time.LoadLocation(postDetail.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func newPreferenceResponse(preference *NotificationPreference) *PreferenceResponse {
+	disabledTypes := []string{}
+	if preference.DisabledTypes != "" {
+		disabledTypes = strings.Split(preference.DisabledTypes, ",")
+	}
+	return &PreferenceResponse{
+		EmailEnabled:  preference.EmailEnabled,
+		SMSEnabled:    preference.SMSEnabled,
+		Locale:        preference.Locale,
+		DisabledTypes: disabledTypes,
+	}
+}
@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationPreference is how a user wants to be notified. DisabledTypes is
+// a comma separated list of notifications the user turned off, e.g.
+// "booking_completed". An empty Locale uses the one of the user's profile.
+type NotificationPreference struct {
+	UserID        uint `gorm:"primaryKey;autoIncrement:false"`
+	EmailEnabled  bool
+	SMSEnabled    bool
+	Locale        string
+	DisabledTypes string
+	UpdatedAt     time.Time
+}
+
+type PreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewPreferenceRepository(db *gorm.DB) *PreferenceRepository {
+	return &PreferenceRepository{db: db}
+}
+
+func (preferenceRepo *PreferenceRepository) UpdatePreference(preference *NotificationPreference) error {
+	return preferenceRepo.db.Save(&preference).Error
+}
+
+func (preferenceRepo *PreferenceRepository) GetPreference(userId uint) (*NotificationPreference, error) {
+	var preference NotificationPreference
+	err := preferenceRepo.db.First(&preference, userId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &preference, nil
+}
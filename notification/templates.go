@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const defaultLocale = "en"
+
+// TemplateData is what the templates can refer to.
+type TemplateData struct {
+	RentRequestID uint
+	PostTitle     string
+	StartDate     string
+	EndDate       string
+	Quantity      int
+	TotalPrice    int
+	PriorityUntil string
+}
+
+type messageTemplate struct {
+	Subject string
+	Body    string
+	SMS     string
+}
+
+// templateSources holds the templates of every notification per locale.
+// Locales missing a template fall back to English.
+var templateSources = map[string]map[string]messageTemplate{
+	"en": {
+		"new_request": {
+			Subject: "New rent request for {{.PostTitle}}",
+			Body:    "You have a new rent request #{{.RentRequestID}} for {{.PostTitle}} from {{.StartDate}} to {{.EndDate}} ({{.Quantity}} unit(s), total {{.TotalPrice}}).\nPlease confirm it or propose other dates.",
+			SMS:     "New rent request #{{.RentRequestID}} for {{.PostTitle}}, {{.StartDate}} - {{.EndDate}}.",
+		},
+		"instant_booking": {
+			Subject: "{{.PostTitle}} was booked instantly",
+			Body:    "Rent request #{{.RentRequestID}} for {{.PostTitle}} from {{.StartDate}} to {{.EndDate}} ({{.Quantity}} unit(s), total {{.TotalPrice}}) was confirmed automatically by your instant booking settings.",
+			SMS:     "{{.PostTitle}} was booked instantly, {{.StartDate}} - {{.EndDate}}.",
+		},
+		"request_confirmed": {
+			Subject: "Your rent request for {{.PostTitle}} is confirmed",
+			Body:    "Your rent request #{{.RentRequestID}} for {{.PostTitle}} from {{.StartDate}} to {{.EndDate}} has been confirmed.\nPay {{.TotalPrice}} to complete your booking.",
+			SMS:     "Rent request #{{.RentRequestID}} for {{.PostTitle}} is confirmed. Pay {{.TotalPrice}} to book it.",
+		},
+		"request_paid": {
+			Subject: "{{.PostTitle}} has been booked",
+			Body:    "Rent request #{{.RentRequestID}} for {{.PostTitle}} from {{.StartDate}} to {{.EndDate}} has been paid ({{.TotalPrice}}).",
+			SMS:     "Rent request #{{.RentRequestID}} for {{.PostTitle}} has been paid.",
+		},
+		"payment_received": {
+			Subject: "Your booking of {{.PostTitle}} is complete",
+			Body:    "We received your payment of {{.TotalPrice}} for rent request #{{.RentRequestID}}. {{.PostTitle}} is yours from {{.StartDate}} to {{.EndDate}}.",
+			SMS:     "Payment received, {{.PostTitle}} is booked from {{.StartDate}} to {{.EndDate}}.",
+		},
+		"request_rejected": {
+			Subject: "Your rent request for {{.PostTitle}} was rejected",
+			Body:    "Unfortunately {{.PostTitle}} is no longer available from {{.StartDate}} to {{.EndDate}}, so your rent request #{{.RentRequestID}} was rejected.",
+			SMS:     "Rent request #{{.RentRequestID}} for {{.PostTitle}} was rejected.",
+		},
+		"request_cancelled": {
+			Subject: "Rent request for {{.PostTitle}} was canceled",
+			Body:    "Rent request #{{.RentRequestID}} for {{.PostTitle}} from {{.StartDate}} to {{.EndDate}} has been canceled.",
+			SMS:     "Rent request #{{.RentRequestID}} for {{.PostTitle}} was canceled.",
+		},
+		"booking_completed": {
+			Subject: "How was {{.PostTitle}}?",
+			Body:    "Your booking #{{.RentRequestID}} of {{.PostTitle}} has ended. We hope you enjoyed it!",
+			SMS:     "Your booking of {{.PostTitle}} has ended. Thank you!",
+		},
+		"waitlist_spot": {
+			Subject: "{{.PostTitle}} is available again",
+			Body:    "{{.PostTitle}} became available from {{.StartDate}} to {{.EndDate}}. It is held for you until {{.PriorityUntil}}, book it before then.",
+			SMS:     "{{.PostTitle}} is available {{.StartDate}} - {{.EndDate}}, held for you until {{.PriorityUntil}}.",
+		},
+	},
+	"fa": {
+		"new_request": {
+			Subject: "درخواست اجاره جدید برای {{.PostTitle}}",
+			Body:    "درخواست اجاره جدید #{{.RentRequestID}} برای {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} ({{.Quantity}} عدد، مبلغ کل {{.TotalPrice}}) دارید.\nلطفا آن را تایید کنید یا تاریخ دیگری پیشنهاد دهید.",
+			SMS:     "درخواست اجاره جدید #{{.RentRequestID}} برای {{.PostTitle}}، {{.StartDate}} تا {{.EndDate}}.",
+		},
+		"instant_booking": {
+			Subject: "{{.PostTitle}} به صورت آنی رزرو شد",
+			Body:    "درخواست اجاره #{{.RentRequestID}} برای {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} ({{.Quantity}} عدد، مبلغ کل {{.TotalPrice}}) طبق تنظیمات رزرو آنی شما به طور خودکار تایید شد.",
+			SMS:     "{{.PostTitle}} به صورت آنی رزرو شد، {{.StartDate}} تا {{.EndDate}}.",
+		},
+		"request_confirmed": {
+			Subject: "درخواست اجاره شما برای {{.PostTitle}} تایید شد",
+			Body:    "درخواست اجاره #{{.RentRequestID}} شما برای {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} تایید شد.\nبرای نهایی کردن رزرو، مبلغ {{.TotalPrice}} را پرداخت کنید.",
+			SMS:     "درخواست اجاره #{{.RentRequestID}} برای {{.PostTitle}} تایید شد. برای رزرو {{.TotalPrice}} پرداخت کنید.",
+		},
+		"request_paid": {
+			Subject: "{{.PostTitle}} رزرو شد",
+			Body:    "درخواست اجاره #{{.RentRequestID}} برای {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} پرداخت شد ({{.TotalPrice}}).",
+			SMS:     "درخواست اجاره #{{.RentRequestID}} برای {{.PostTitle}} پرداخت شد.",
+		},
+		"payment_received": {
+			Subject: "رزرو {{.PostTitle}} انجام شد",
+			Body:    "پرداخت {{.TotalPrice}} برای درخواست اجاره #{{.RentRequestID}} دریافت شد. {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} برای شما رزرو شد.",
+			SMS:     "پرداخت دریافت شد، {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} رزرو شد.",
+		},
+		"request_rejected": {
+			Subject: "درخواست اجاره شما برای {{.PostTitle}} رد شد",
+			Body:    "متاسفانه {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} دیگر در دسترس نیست و درخواست اجاره #{{.RentRequestID}} شما رد شد.",
+			SMS:     "درخواست اجاره #{{.RentRequestID}} برای {{.PostTitle}} رد شد.",
+		},
+		"request_cancelled": {
+			Subject: "درخواست اجاره {{.PostTitle}} لغو شد",
+			Body:    "درخواست اجاره #{{.RentRequestID}} برای {{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} لغو شد.",
+			SMS:     "درخواست اجاره #{{.RentRequestID}} برای {{.PostTitle}} لغو شد.",
+		},
+		"booking_completed": {
+			Subject: "{{.PostTitle}} چطور بود؟",
+			Body:    "رزرو #{{.RentRequestID}} شما برای {{.PostTitle}} به پایان رسید. امیدواریم از آن لذت برده باشید!",
+			SMS:     "رزرو {{.PostTitle}} به پایان رسید. سپاسگزاریم!",
+		},
+		"waitlist_spot": {
+			Subject: "{{.PostTitle}} دوباره در دسترس است",
+			Body:    "{{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} در دسترس قرار گرفت و تا {{.PriorityUntil}} برای شما نگه داشته می‌شود. تا قبل از آن رزرو کنید.",
+			SMS:     "{{.PostTitle}} از {{.StartDate}} تا {{.EndDate}} در دسترس است و تا {{.PriorityUntil}} برای شما نگه داشته می‌شود.",
+		},
+	},
+}
+
+var templates = parseTemplates()
+
+func parseTemplates() map[string]map[string]*template.Template {
+	parsed := map[string]map[string]*template.Template{}
+	for locale, localeTemplates := range templateSources {
+		parsed[locale] = map[string]*template.Template{}
+		for name, source := range localeTemplates {
+			for part, text := range map[string]string{"subject": source.Subject, "body": source.Body, "sms": source.SMS} {
+				parsed[locale][name+"."+part] = template.Must(template.New(name + "." + part).Parse(text))
+			}
+		}
+	}
+	return parsed
+}
+
+// render renders one part ("subject", "body" or "sms") of a notification in
+// the language of the given locale, e.g. "fa" for "fa-IR", falling back to
+// English.
+func render(locale, name, part string, data TemplateData) (string, error) {
+	key := name + "." + part
+	language, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	tmpl, ok := templates[language][key]
+	if !ok {
+		tmpl, ok = templates[defaultLocale][key]
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown notification template %q", key)
+	}
+
+	var rendered strings.Builder
+	err := tmpl.Execute(&rendered, data)
+	if err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,210 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	historySize      = 1024
+	subscriberBuffer = 64
+)
+
+// Event is a change pushed to the users it concerns. IDs are
+// "<hub start>-<sequence>" so an ID from before a restart is recognized and
+// not resumed from.
+type Event struct {
+	ID      string
+	Seq     uint64
+	Type    string
+	Data    json.RawMessage
+	UserIDs []uint
+}
+
+// Subscription receives the events of one user. Events is closed when the
+// subscriber is too slow to keep up, in which case it should resume from the
+// last event it handled, or when the hub is closed.
+type Subscription struct {
+	UserID uint
+	Events chan Event
+}
+
+// Hub is an in-process pub/sub of changes per user. It keeps the latest
+// events so clients reconnecting shortly after a disconnect can catch up.
+type Hub struct {
+	mu          sync.Mutex
+	epoch       int64
+	seq         uint64
+	history     []Event
+	subscribers map[uint]map[*Subscription]struct{}
+	closed      chan struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		epoch:       time.Now().UnixNano(),
+		subscribers: map[uint]map[*Subscription]struct{}{},
+		closed:      make(chan struct{}),
+	}
+}
+
+// Close ends every subscription, and the ones made afterwards, so the open
+// streams return when the server shuts down.
+func (hub *Hub) Close() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	select {
+	case <-hub.closed:
+		return
+	default:
+	}
+	close(hub.closed)
+	for _, userSubscriptions := range hub.subscribers {
+		for subscription := range userSubscriptions {
+			hub.unsubscribeLocked(subscription)
+		}
+	}
+}
+
+// Closed is closed once the hub is.
+func (hub *Hub) Closed() <-chan struct{} {
+	return hub.closed
+}
+
+// Broadcast publishes an event to the given users.
+func (hub *Hub) Broadcast(userIds []uint, eventType string, data interface{}) {
+	hub.publish(userIds, eventType, data, true)
+}
+
+// Notify publishes an ephemeral event, like a typing indicator, that has no
+// ID and is not replayed to reconnecting clients.
+func (hub *Hub) Notify(userIds []uint, eventType string, data interface{}) {
+	hub.publish(userIds, eventType, data, false)
+}
+
+func (hub *Hub) publish(userIds []uint, eventType string, data interface{}, keep bool) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		zap.L().Error("error encoding realtime event", zap.String("type", eventType), zap.Error(err))
+		return
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	event := Event{
+		Type:    eventType,
+		Data:    body,
+		UserIDs: userIds,
+	}
+	if keep {
+		hub.seq++
+		event.ID = fmt.Sprintf("%d-%d", hub.epoch, hub.seq)
+		event.Seq = hub.seq
+		hub.history = append(hub.history, event)
+		if len(hub.history) > historySize {
+			hub.history = hub.history[len(hub.history)-historySize:]
+		}
+	}
+
+	delivered := map[uint]bool{}
+	for _, userId := range userIds {
+		if delivered[userId] {
+			continue
+		}
+		delivered[userId] = true
+		for subscription := range hub.subscribers[userId] {
+			select {
+			case subscription.Events <- event:
+			default:
+				hub.unsubscribeLocked(subscription)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscriber for the user and returns the events it
+// missed since lastEventId. resumed is false when those events are no longer
+// all known, e.g. after a restart, and the client has to reload its state.
+func (hub *Hub) Subscribe(userId uint, lastEventId string) (subscription *Subscription, missed []Event, resumed bool) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	subscription = &Subscription{UserID: userId, Events: make(chan Event, subscriberBuffer)}
+	select {
+	case <-hub.closed:
+		close(subscription.Events)
+		return subscription, nil, true
+	default:
+	}
+	if hub.subscribers[userId] == nil {
+		hub.subscribers[userId] = map[*Subscription]struct{}{}
+	}
+	hub.subscribers[userId][subscription] = struct{}{}
+
+	if lastEventId == "" {
+		return subscription, nil, true
+	}
+
+	lastSeq, ok := hub.parseID(lastEventId)
+	if !ok || lastSeq > hub.seq {
+		return subscription, nil, false
+	}
+	if len(hub.history) > 0 && lastSeq+1 < hub.history[0].Seq {
+		return subscription, nil, false
+	}
+
+	for _, event := range hub.history {
+		if event.Seq > lastSeq && concerns(event, userId) {
+			missed = append(missed, event)
+		}
+	}
+	return subscription, missed, true
+}
+
+func (hub *Hub) Unsubscribe(subscription *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.unsubscribeLocked(subscription)
+}
+
+func (hub *Hub) unsubscribeLocked(subscription *Subscription) {
+	userSubscriptions := hub.subscribers[subscription.UserID]
+	if _, ok := userSubscriptions[subscription]; !ok {
+		return
+	}
+	delete(userSubscriptions, subscription)
+	if len(userSubscriptions) == 0 {
+		delete(hub.subscribers, subscription.UserID)
+	}
+	close(subscription.Events)
+}
+
+func (hub *Hub) parseID(eventId string) (uint64, bool) {
+	epochStr, seqStr, ok := strings.Cut(eventId, "-")
+	if !ok || epochStr != strconv.FormatInt(hub.epoch, 10) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
+
+func concerns(event Event, userId uint) bool {
+	for _, eventUserId := range event.UserIDs {
+		if eventUserId == userId {
+			return true
+		}
+	}
+	return false
+}
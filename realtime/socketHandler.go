@@ -0,0 +1,383 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rental_service/auth"
+	"rental_service/rent"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	writeTimeout   = 10 * time.Second
+	pongTimeout    = 60 * time.Second
+	pingInterval   = 25 * time.Second
+	maxFrameSize   = 64 * 1024
+	outboundBuffer = 64
+	typingEvent    = "typing"
+	presenceEvent  = "presence"
+)
+
+// clientFrame is a frame sent by the client:
+//
+//	{"type": "join", "rentRequestId": 1}
+//	{"type": "leave", "rentRequestId": 1}
+//	{"type": "message", "rentRequestId": 1, "body": "hi", "ref": "c-1"}
+//	{"type": "typing", "rentRequestId": 1, "typing": true}
+//
+// Ref is echoed back in the error frame if the frame fails.
+type clientFrame struct {
+	Type          string `json:"type"`
+	RentRequestID uint   `json:"rentRequestId"`
+	Body          string `json:"body"`
+	Typing        bool   `json:"typing"`
+	Ref           string `json:"ref"`
+}
+
+// serverFrame is a frame sent to the client. Events carry the same ID, type
+// and data as the SSE stream; ephemeral ones (presence, typing) have no ID.
+type serverFrame struct {
+	Type          string          `json:"type"`
+	ID            string          `json:"id,omitempty"`
+	Event         string          `json:"event,omitempty"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	Resumed       *bool           `json:"resumed,omitempty"`
+	RentRequestID uint            `json:"rentRequestId,omitempty"`
+	Online        []uint          `json:"online,omitempty"`
+	Ref           string          `json:"ref,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+type SocketHandler struct {
+	hub            *Hub
+	messageService *rent.MessageService
+	revocations    auth.TokenRevocations
+	presence       *presence
+	upgrader       websocket.Upgrader
+
+	validate *validator.Validate
+}
+
+func NewSocketHandler(hub *Hub, messageService *rent.MessageService, revocations auth.TokenRevocations, validate *validator.Validate) *SocketHandler {
+	return &SocketHandler{
+		hub:            hub,
+		messageService: messageService,
+		revocations:    revocations,
+		presence:       newPresence(),
+		upgrader: websocket.Upgrader{
+			// Browsers cannot send the Authorization header on WebSockets so
+			// the token comes in the URL, which another site cannot forge.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		validate: validate,
+	}
+}
+
+// Connect upgrades the request to a WebSocket multiplexing the booking
+// changes and message threads of every rent request of the caller. Joining a
+// thread shares the caller's presence and typing with the other participant.
+//
+// Clients reconnect with the ID of the last event they handled in the
+// lastEventId query parameter; the first frame tells whether the missed events
+// follow ("resumed": true) or the client has to reload its data. Threads have
+// to be joined again after a reconnection. The socket is closed once the
+// token it was opened with is revoked or expires.
+func (handler *SocketHandler) Connect(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	conn, err := handler.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		zap.L().Error("error upgrading to websocket", zap.Error(err))
+		return nil
+	}
+
+	principal, _ := auth.GetPrincipal(c)
+	client := &socketClient{
+		handler:   handler,
+		userId:    userId,
+		principal: principal,
+		conn:      conn,
+		outbound:  make(chan serverFrame, outboundBuffer),
+		done:      make(chan struct{}),
+		joined:    map[uint][]uint{},
+	}
+	client.run(c.QueryParam("lastEventId"))
+	return nil
+}
+
+type socketClient struct {
+	handler   *SocketHandler
+	userId    uint
+	principal *auth.Principal
+	conn      *websocket.Conn
+	outbound  chan serverFrame
+	done      chan struct{}
+	once      sync.Once
+
+	// joined maps the threads joined by this connection to their participants.
+	joined map[uint][]uint
+}
+
+func (client *socketClient) run(lastEventId string) {
+	hub := client.handler.hub
+	subscription, missed, resumed := hub.Subscribe(client.userId, lastEventId)
+	defer hub.Unsubscribe(subscription)
+	defer client.leaveAll()
+
+	go client.writeLoop()
+
+	client.send(serverFrame{Type: "welcome", Resumed: &resumed})
+	for _, event := range missed {
+		client.send(eventFrame(event))
+	}
+
+	go func() {
+		for event := range subscription.Events {
+			client.send(eventFrame(event))
+		}
+		// The subscription is closed when the server shuts down or the client
+		// cannot keep up; either way it reconnects and resumes from its last
+		// event.
+		select {
+		case <-hub.Closed():
+			client.close(websocket.CloseGoingAway, "server shutting down")
+		default:
+			client.close(websocket.CloseTryAgainLater, "too slow, resume from your last event")
+		}
+	}()
+
+	client.readLoop()
+	client.close(websocket.CloseNormalClosure, "")
+}
+
+func (client *socketClient) readLoop() {
+	client.conn.SetReadLimit(maxFrameSize)
+	client.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		var frame clientFrame
+		err := client.conn.ReadJSON(&frame)
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				client.send(serverFrame{Type: "error", Error: "invalid frame"})
+				continue
+			}
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				zap.L().Info("websocket closed", zap.Uint("userId", client.userId), zap.Error(err))
+			}
+			return
+		}
+
+		// Frames act on behalf of the user, like sending messages.
+		if client.sessionEnded() {
+			return
+		}
+		err = client.handle(frame)
+		if err != nil {
+			client.send(serverFrame{Type: "error", RentRequestID: frame.RentRequestID, Ref: frame.Ref, Error: frameError(err)})
+		}
+	}
+}
+
+func (client *socketClient) handle(frame clientFrame) error {
+	hub := client.handler.hub
+	switch frame.Type {
+	case "join":
+		if _, ok := client.joined[frame.RentRequestID]; ok {
+			return nil
+		}
+		participants, err := client.handler.messageService.GetThreadParticipants(client.userId, strconv.FormatUint(uint64(frame.RentRequestID), 10))
+		if err != nil {
+			return err
+		}
+		client.joined[frame.RentRequestID] = participants
+
+		first, online := client.handler.presence.join(frame.RentRequestID, client.userId)
+		client.send(serverFrame{Type: "joined", RentRequestID: frame.RentRequestID, Online: online})
+		if first {
+			hub.Notify(participants, presenceEvent, map[string]interface{}{"rentRequestId": frame.RentRequestID, "userId": client.userId, "online": true})
+		}
+		return nil
+	case "leave":
+		client.leave(frame.RentRequestID)
+		return nil
+	case "message":
+		messageDto := rent.MessageDto{Body: frame.Body}
+		if err := client.handler.validate.Struct(messageDto); err != nil {
+			return errInvalidFrame
+		}
+		_, err := client.handler.messageService.SendMessage(client.userId, strconv.FormatUint(uint64(frame.RentRequestID), 10), messageDto)
+		return err
+	case "typing":
+		participants, ok := client.joined[frame.RentRequestID]
+		if !ok {
+			return errNotJoined
+		}
+		hub.Notify(participants, typingEvent, map[string]interface{}{"rentRequestId": frame.RentRequestID, "userId": client.userId, "typing": frame.Typing})
+		return nil
+	}
+	return errInvalidFrame
+}
+
+var errInvalidFrame = errors.New("invalid frame")
+var errNotJoined = errors.New("join the thread first")
+
+func frameError(err error) string {
+	switch {
+	case errors.Is(err, rent.ErrRecordNotFound):
+		return "rent request not found"
+	case errors.Is(err, rent.ErrNotAllowed):
+		return "forbidden Access"
+	case errors.Is(err, errInvalidFrame), errors.Is(err, errNotJoined):
+		return err.Error()
+	}
+	zap.L().Error("error handling websocket frame", zap.Error(err))
+	return "internal error"
+}
+
+func (client *socketClient) leave(rentRequestId uint) {
+	participants, ok := client.joined[rentRequestId]
+	if !ok {
+		return
+	}
+	delete(client.joined, rentRequestId)
+
+	if client.handler.presence.leave(rentRequestId, client.userId) {
+		client.handler.hub.Notify(participants, presenceEvent, map[string]interface{}{"rentRequestId": rentRequestId, "userId": client.userId, "online": false})
+	}
+}
+
+func (client *socketClient) leaveAll() {
+	for rentRequestId := range client.joined {
+		client.leave(rentRequestId)
+	}
+}
+
+// send queues a frame for the writer. A client whose queue is full is
+// disconnected like a slow subscriber.
+func (client *socketClient) send(frame serverFrame) {
+	select {
+	case <-client.done:
+	case client.outbound <- frame:
+	default:
+		client.close(websocket.CloseTryAgainLater, "too slow, resume from your last event")
+	}
+}
+
+// writeLoop is the only goroutine writing to the connection.
+func (client *socketClient) writeLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-client.done:
+			return
+		case frame := <-client.outbound:
+			if client.sessionEnded() {
+				return
+			}
+			client.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := client.conn.WriteJSON(frame); err != nil {
+				client.close(websocket.CloseInternalServerErr, "")
+				return
+			}
+		case <-ticker.C:
+			if client.sessionEnded() {
+				return
+			}
+			if err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				client.close(websocket.CloseInternalServerErr, "")
+				return
+			}
+		}
+	}
+}
+
+// sessionEnded closes the socket once the token it was opened with is
+// revoked or expired, and tells whether it did.
+func (client *socketClient) sessionEnded() bool {
+	switch {
+	case sessionRevoked(client.principal, client.handler.revocations):
+		client.close(websocket.ClosePolicyViolation, "session revoked")
+	case sessionExpired(client.principal):
+		client.close(websocket.ClosePolicyViolation, "token expired")
+	default:
+		return false
+	}
+	return true
+}
+
+// close sends a close frame with the given code and closes the connection,
+// which also ends the read loop.
+func (client *socketClient) close(code int, reason string) {
+	client.once.Do(func() {
+		close(client.done)
+		message := websocket.FormatCloseMessage(code, reason)
+		client.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeTimeout))
+		client.conn.Close()
+	})
+}
+
+func eventFrame(event Event) serverFrame {
+	return serverFrame{Type: "event", ID: event.ID, Event: event.Type, Data: event.Data}
+}
+
+// presence counts the connections of each user that joined a thread, so a
+// user is only shown offline once their last tab leaves.
+type presence struct {
+	mu     sync.Mutex
+	online map[uint]map[uint]int
+}
+
+func newPresence() *presence {
+	return &presence{online: map[uint]map[uint]int{}}
+}
+
+func (presence *presence) join(rentRequestId, userId uint) (first bool, online []uint) {
+	presence.mu.Lock()
+	defer presence.mu.Unlock()
+
+	if presence.online[rentRequestId] == nil {
+		presence.online[rentRequestId] = map[uint]int{}
+	}
+	presence.online[rentRequestId][userId]++
+	for onlineUserId := range presence.online[rentRequestId] {
+		online = append(online, onlineUserId)
+	}
+	sort.Slice(online, func(i, j int) bool { return online[i] < online[j] })
+	return presence.online[rentRequestId][userId] == 1, online
+}
+
+func (presence *presence) leave(rentRequestId, userId uint) (last bool) {
+	presence.mu.Lock()
+	defer presence.mu.Unlock()
+
+	presence.online[rentRequestId][userId]--
+	if presence.online[rentRequestId][userId] > 0 {
+		return false
+	}
+	delete(presence.online[rentRequestId], userId)
+	if len(presence.online[rentRequestId]) == 0 {
+		delete(presence.online, rentRequestId)
+	}
+	return true
+}
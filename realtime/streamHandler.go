@@ -0,0 +1,110 @@
+package realtime
+
+import (
+	"fmt"
+	"net/http"
+	"rental_service/auth"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const heartbeatInterval = 25 * time.Second
+
+type StreamHandler struct {
+	hub         *Hub
+	revocations auth.TokenRevocations
+}
+
+func NewStreamHandler(hub *Hub, revocations auth.TokenRevocations) *StreamHandler {
+	return &StreamHandler{hub: hub, revocations: revocations}
+}
+
+// Stream sends the changes concerning the caller as Server-Sent Events. A
+// client reconnecting with Last-Event-ID (or the lastEventId query parameter)
+// first receives the events it missed. When they are not known anymore a
+// "reset" event tells it to reload its data. The stream ends once the token
+// it was opened with is revoked or expires; reconnecting with it is then
+// refused.
+func (handler *StreamHandler) Stream(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	principal, _ := auth.GetPrincipal(c)
+
+	lastEventId := c.Request().Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.QueryParam("lastEventId")
+	}
+
+	subscription, missed, resumed := handler.hub.Subscribe(userId, lastEventId)
+	defer handler.hub.Unsubscribe(subscription)
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("Connection", "keep-alive")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(response, "retry: 3000\n\n")
+	if !resumed {
+		fmt.Fprint(response, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		writeEvent(response, event)
+	}
+	response.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// Too slow to keep up or shutting down, the client resumes
+				// from its last event.
+				return nil
+			}
+			if sessionRevoked(principal, handler.revocations) || sessionExpired(principal) {
+				return nil
+			}
+			writeEvent(response, event)
+			response.Flush()
+		case <-heartbeat.C:
+			if sessionRevoked(principal, handler.revocations) || sessionExpired(principal) {
+				return nil
+			}
+			fmt.Fprint(response, ": ping\n\n")
+			response.Flush()
+		}
+	}
+}
+
+// writeEvent leaves the id out for ephemeral events so they do not move the
+// client's Last-Event-ID.
+func writeEvent(response *echo.Response, event Event) {
+	if event.ID != "" {
+		fmt.Fprintf(response, "id: %s\n", event.ID)
+	}
+	fmt.Fprintf(response, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+}
+
+// sessionRevoked tells whether the token the connection was opened with has
+// been revoked since, e.g. when the user was logged out everywhere.
+func sessionRevoked(principal *auth.Principal, revocations auth.TokenRevocations) bool {
+	return principal != nil && principal.IsRevoked(revocations)
+}
+
+// sessionExpired tells whether the token the connection was opened with has
+// expired since.
+func sessionExpired(principal *auth.Principal) bool {
+	return principal != nil && !principal.ExpiresAt.IsZero() && time.Now().After(principal.ExpiresAt)
+}
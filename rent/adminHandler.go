@@ -0,0 +1,115 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type AdminSearchDto struct {
+	Status         string `query:"status"`
+	PaymentStatus  string `query:"paymentStatus"`
+	RenterID       uint   `query:"renterId"`
+	OwnerID        uint   `query:"ownerId"`
+	PostID         uint   `query:"postId"`
+	BookingGroupID uint   `query:"bookingGroupId"`
+	Date           string `query:"date"`
+	Page           int    `query:"page"`
+	PageSize       int    `query:"pageSize" validate:"omitempty,max=100"`
+}
+
+// TransitionDto forces the status, and optionally the payment status, of a
+// rent request. The reason is mandatory and kept with the request.
+type TransitionDto struct {
+	Status        string `json:"status" validate:"required,oneof=Confirmed paid Rejected canceled completed"`
+	PaymentStatus string `json:"paymentStatus" validate:"omitempty,oneof=success cancel refunded"`
+	Reason        string `json:"reason" validate:"required,min=10,max=1000"`
+}
+
+func (handler *RentHandler) SearchRentRequests(c echo.Context) error {
+	var searchDto AdminSearchDto
+	if err := c.Bind(&searchDto); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(searchDto); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	rents, err := handler.service.SearchRentRequests(searchDto)
+	if err != nil {
+		if errors.Is(err, ErrInvalidDateRange) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidDateRange.Error())
+		}
+		zap.L().Error("error searching rents", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch rents")
+	}
+
+	return c.JSON(http.StatusOK, rents)
+}
+
+func (handler *RentHandler) GetAdminRentRequest(c echo.Context) error {
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	rentRequest, err := handler.service.GetAdminRentRequest(rentRequestIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		}
+		zap.L().Error("error retrieving rentRequest", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve rent request")
+	}
+
+	return c.JSON(http.StatusOK, rentRequest)
+}
+
+func (handler *RentHandler) TransitionRentRequest(c echo.Context) error {
+	var transitionDto TransitionDto
+
+	adminId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	if err := c.Bind(&transitionDto); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	transitionDto.Reason = strings.TrimSpace(transitionDto.Reason)
+	if err := handler.validate.Struct(transitionDto); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data, a reason of at least 10 characters is required")
+	}
+
+	rentRequest, err := handler.service.TransitionRentRequest(adminId, rentRequestIdStr, transitionDto)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrInvalidTransition) {
+			return c.JSON(http.StatusConflict, ErrInvalidTransition.Error())
+		} else if errors.Is(err, ErrConflict) {
+			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		}
+		zap.L().Error("error transitioning rentRequest", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to transition rent request")
+	}
+
+	return c.JSON(http.StatusOK, rentRequest)
+}
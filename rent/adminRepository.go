@@ -0,0 +1,95 @@
+package rent
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdminAction records a change made by an admin to a rent request, with the
+// reason they gave.
+type AdminAction struct {
+	ID                uint
+	RentRequestID     uint
+	AdminID           uint
+	FromStatus        string
+	ToStatus          string
+	FromPaymentStatus string
+	ToPaymentStatus   string
+	Reason            string
+	CreatedAt         time.Time
+}
+
+type RentRequestFilter struct {
+	Status         string
+	PaymentStatus  string
+	RenterID       uint
+	OwnerID        uint
+	PostID         uint
+	BookingGroupID uint
+	MinDate        *time.Time
+	MaxDate        *time.Time
+}
+
+// SearchRentRequests returns a page of the rent requests of every user
+// matching the filter, newest first, along with the number of matches.
+func (rentRepo *RentRepository) SearchRentRequests(filter RentRequestFilter, offset, limit int) ([]RentRequest, int64, error) {
+	query := rentRepo.db.Model(&RentRequest{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.PaymentStatus != "" {
+		query = query.Where("payment_status = ?", filter.PaymentStatus)
+	}
+	if filter.RenterID != 0 {
+		query = query.Where("renter_id = ?", filter.RenterID)
+	}
+	if filter.OwnerID != 0 {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.PostID != 0 {
+		query = query.Where("post_id = ?", filter.PostID)
+	}
+	if filter.BookingGroupID != 0 {
+		query = query.Where("booking_group_id = ?", filter.BookingGroupID)
+	}
+	if filter.MinDate != nil {
+		query = query.Where("created_at >= ?", *filter.MinDate)
+	}
+	if filter.MaxDate != nil {
+		query = query.Where("created_at <= ?", *filter.MaxDate)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var rentRequestList []RentRequest
+	err = query.Order("id DESC").Offset(offset).Limit(limit).Find(&rentRequestList).Error
+	return rentRequestList, count, err
+}
+
+// TransitionRentRequestWithEvent saves a rent request changed by an admin
+// along with its domain event and the record of the action.
+func (rentRepo *RentRepository) TransitionRentRequestWithEvent(rentRequest *RentRequest, eventType string, action *AdminAction) error {
+	return rentRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(&rentRequest).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&action).Error
+		if err != nil {
+			return err
+		}
+		return enqueueRentRequestEvent(tx, eventType, rentRequest)
+	})
+}
+
+func (rentRepo *RentRepository) GetAdminActions(rentRequestId uint) ([]AdminAction, error) {
+	var actions []AdminAction
+	err := rentRepo.db.Where("rent_request_id = ?", rentRequestId).Order("created_at").Find(&actions).Error
+	return actions, err
+}
@@ -0,0 +1,259 @@
+package rent
+
+import (
+	"errors"
+	"rental_service/events"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidTransition = errors.New("the rent request already has this status")
+var ErrInvalidDateRange = errors.New("date must be \"min,max\" with dates as YYYY-MM-DD and min before max")
+
+// transitionEvents maps the statuses an admin can force to the event
+// published for the change.
+var transitionEvents = map[string]string{
+	"Confirmed": events.RentRequestConfirmed,
+	"paid":      events.RentRequestPaid,
+	"Rejected":  events.RentRequestRejected,
+	"canceled":  events.RentRequestCancelled,
+	"completed": events.RentRequestCompleted,
+}
+
+type AdminRentRequestResponse struct {
+	ID              uint                  `json:"id"`
+	RenterID        uint                  `json:"renter_id"`
+	OwnerID         uint                  `json:"owner_id"`
+	PostID          uint                  `json:"post_id"`
+	ParentRequestID *uint                 `json:"parent_request_id,omitempty"`
+	BookingGroupID  *uint                 `json:"booking_group_id,omitempty"`
+	StartDate       time.Time             `json:"start_date"`
+	EndDate         time.Time             `json:"end_date"`
+	Quantity        int                   `json:"quantity"`
+	TotalPrice      int                   `json:"total_price"`
+	Status          string                `json:"status"`
+	PaymentStatus   string                `json:"payment_status"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+	Actions         []AdminActionResponse `json:"actions,omitempty"`
+}
+
+type AdminActionResponse struct {
+	AdminID           uint      `json:"admin_id"`
+	FromStatus        string    `json:"from_status"`
+	ToStatus          string    `json:"to_status"`
+	FromPaymentStatus string    `json:"from_payment_status"`
+	ToPaymentStatus   string    `json:"to_payment_status"`
+	Reason            string    `json:"reason"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type AdminSearchResponse struct {
+	Total        int64                      `json:"total"`
+	RentRequests []AdminRentRequestResponse `json:"rent_requests"`
+}
+
+// SearchRentRequests searches the rent requests of every user. The date is
+// "min,max" on the creation date, like for the owner and renter lists.
+func (service *RentService) SearchRentRequests(searchDto AdminSearchDto) (*AdminSearchResponse, error) {
+	filter := RentRequestFilter{
+		Status:         searchDto.Status,
+		PaymentStatus:  searchDto.PaymentStatus,
+		RenterID:       searchDto.RenterID,
+		OwnerID:        searchDto.OwnerID,
+		PostID:         searchDto.PostID,
+		BookingGroupID: searchDto.BookingGroupID,
+	}
+
+	var err error
+	filter.MinDate, filter.MaxDate, err = parseDateRange(searchDto.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	page := searchDto.Page
+	if page < 1 {
+		page = 1
+	}
+	size := searchDto.PageSize
+	if size < 1 {
+		size = 20
+	}
+
+	rents, total, err := service.repo.SearchRentRequests(filter, (page-1)*size, size)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AdminSearchResponse{Total: total, RentRequests: []AdminRentRequestResponse{}}
+	for i := range rents {
+		response.RentRequests = append(response.RentRequests, newAdminRentRequestResponse(&rents[i], nil))
+	}
+	return response, nil
+}
+
+func (service *RentService) GetAdminRentRequest(rentRequestIdStr string) (*AdminRentRequestResponse, error) {
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := service.repo.GetAdminActions(rentRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := newAdminRentRequestResponse(rentRequest, actions)
+	return &response, nil
+}
+
+// TransitionRentRequest forces the status of a rent request, whatever its
+// current status, and records who did it and why. It publishes the event of
+// the new status like a regular change but does not move money: refunds and
+// payments are settled with the payment service separately. Requests made
+// Confirmed or paid must still fit in the inventory of the post, paying one
+// rejects the requests it leaves no room for and calling off a paid one
+// releases the waitlist.
+func (service *RentService) TransitionRentRequest(adminId uint, rentRequestIdStr string, transitionDto TransitionDto) (*AdminRentRequestResponse, error) {
+	eventType, ok := transitionEvents[transitionDto.Status]
+	if !ok {
+		return nil, ErrInvalidTransition
+	}
+
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentStatus := rentRequest.PaymentStatus
+	if transitionDto.PaymentStatus != "" {
+		paymentStatus = transitionDto.PaymentStatus
+	}
+	if rentRequest.Status == transitionDto.Status && rentRequest.PaymentStatus == paymentStatus {
+		return nil, ErrInvalidTransition
+	}
+
+	if transitionDto.Status == "Confirmed" || transitionDto.Status == "paid" {
+		postDetail, err := service.serviceClient.GetPostByID(rentRequest.PostID)
+		if err != nil {
+			return nil, err
+		}
+		err = service.checkAvailability(rentRequest.PostID, inventory(postDetail), bookedQuantity(rentRequest), rentRequest.StartDate, rentRequest.EndDate, rentRequest.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	action := &AdminAction{
+		RentRequestID:     rentRequest.ID,
+		AdminID:           adminId,
+		FromStatus:        rentRequest.Status,
+		ToStatus:          transitionDto.Status,
+		FromPaymentStatus: rentRequest.PaymentStatus,
+		ToPaymentStatus:   paymentStatus,
+		Reason:            transitionDto.Reason,
+		CreatedAt:         time.Now(),
+	}
+
+	rentRequest.Status = transitionDto.Status
+	rentRequest.PaymentStatus = paymentStatus
+	rentRequest.UpdatedAt = time.Now()
+
+	err = service.repo.TransitionRentRequestWithEvent(rentRequest, eventType, action)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("rent request transitioned by admin",
+		zap.Uint("rentRequestId", rentRequest.ID),
+		zap.Uint("adminId", adminId),
+		zap.String("fromStatus", action.FromStatus),
+		zap.String("toStatus", action.ToStatus),
+		zap.String("fromPaymentStatus", action.FromPaymentStatus),
+		zap.String("toPaymentStatus", action.ToPaymentStatus),
+		zap.String("reason", action.Reason))
+	service.broadcastChange(eventType, rentRequest)
+
+	if rentRequest.Status != "Confirmed" && rentRequest.Status != "paid" {
+		service.cancelOpenModifications(rentRequest.ID)
+	}
+
+	// Only paid requests hold units, canceling or rejecting one frees its
+	// period for the waitlisted renters.
+	if action.FromStatus == "paid" && (rentRequest.Status == "canceled" || rentRequest.Status == "Rejected") {
+		service.releaseWaitlist(rentRequest.PostID, rentRequest.StartDate, rentRequest.EndDate)
+	}
+
+	if rentRequest.Status == "paid" {
+		err = service.rejectOverlappingRequests(rentRequest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = service.refreshBookingGroup(rentRequest.BookingGroupID)
+	if err != nil {
+		return nil, err
+	}
+	return service.GetAdminRentRequest(strconv.FormatUint(uint64(rentRequest.ID), 10))
+}
+
+func parseDateRange(dateStr string) (*time.Time, *time.Time, error) {
+	if dateStr == "" {
+		return nil, nil, nil
+	}
+
+	var minDate, maxDate *time.Time
+	minStr, maxStr, _ := strings.Cut(dateStr, ",")
+	if minStr != "" {
+		min, err := time.Parse("2006-01-02", minStr)
+		if err != nil {
+			return nil, nil, ErrInvalidDateRange
+		}
+		minDate = &min
+	}
+	if maxStr != "" {
+		max, err := time.Parse("2006-01-02", maxStr)
+		if err != nil {
+			return nil, nil, ErrInvalidDateRange
+		}
+		maxDate = &max
+	}
+	if minDate != nil && maxDate != nil && minDate.After(*maxDate) {
+		return nil, nil, ErrInvalidDateRange
+	}
+	return minDate, maxDate, nil
+}
+
+func newAdminRentRequestResponse(rentRequest *RentRequest, actions []AdminAction) AdminRentRequestResponse {
+	response := AdminRentRequestResponse{
+		ID:              rentRequest.ID,
+		RenterID:        rentRequest.RenterID,
+		OwnerID:         rentRequest.OwnerID,
+		PostID:          rentRequest.PostID,
+		ParentRequestID: rentRequest.ParentRequestID,
+		BookingGroupID:  rentRequest.BookingGroupID,
+		StartDate:       rentRequest.StartDate,
+		EndDate:         rentRequest.EndDate,
+		Quantity:        bookedQuantity(rentRequest),
+		TotalPrice:      rentRequest.TotalPrice,
+		Status:          rentRequest.Status,
+		PaymentStatus:   rentRequest.PaymentStatus,
+		CreatedAt:       rentRequest.CreatedAt,
+		UpdatedAt:       rentRequest.UpdatedAt,
+	}
+	for _, action := range actions {
+		response.Actions = append(response.Actions, AdminActionResponse{
+			AdminID:           action.AdminID,
+			FromStatus:        action.FromStatus,
+			ToStatus:          action.ToStatus,
+			FromPaymentStatus: action.FromPaymentStatus,
+			ToPaymentStatus:   action.ToPaymentStatus,
+			Reason:            action.Reason,
+			CreatedAt:         action.CreatedAt,
+		})
+	}
+	return response
+}
@@ -0,0 +1,146 @@
+package rent
+
+import (
+	"errors"
+	"rental_service/events"
+	"sort"
+	"time"
+)
+
+// inventory is the number of units of a post that can be rented at the same
+// time. Posts without a quantity are single items.
+func inventory(postDetail *PostResponseWithOwner) int {
+	if postDetail.Quantity > 0 {
+		return postDetail.Quantity
+	}
+	return 1
+}
+
+// checkAvailability returns ErrConflict when renting quantity more units of
+// the post over the period would make the paid bookings exceed its inventory
+// at any point. excludeId lets a request be checked against everything but
+// itself.
+func (service *RentService) checkAvailability(postId uint, inventory, quantity int, startDate, endDate time.Time, excludeId uint) error {
+	bookedUnits, err := service.bookedUnits(postId, startDate, endDate, excludeId)
+	if err != nil {
+		return err
+	}
+
+	if bookedUnits+quantity > inventory {
+		return ErrConflict
+	}
+	return nil
+}
+
+// bookedUnits returns the highest number of units of the post covered by paid
+// requests at the same time within the period.
+func (service *RentService) bookedUnits(postId uint, startDate, endDate time.Time, excludeId uint) (int, error) {
+	rentRequestList, err := service.repo.GetOvelappingRequest(postId, "paid", startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+
+	var bookings []RentRequest
+	for _, overlappingRequest := range rentRequestList {
+		if overlappingRequest.ID != excludeId {
+			bookings = append(bookings, overlappingRequest)
+		}
+	}
+
+	return maxBookedUnits(bookings, startDate, endDate), nil
+}
+
+// maxBookedUnits returns the highest number of units booked at the same time
+// within the period. Bookings are half-open, so one ending when another
+// starts does not count twice.
+func maxBookedUnits(bookings []RentRequest, startDate, endDate time.Time) int {
+	type change struct {
+		at    time.Time
+		units int
+	}
+
+	var changes []change
+	for _, booking := range bookings {
+		bookingStart := booking.StartDate
+		if bookingStart.Before(startDate) {
+			bookingStart = startDate
+		}
+		bookingEnd := booking.EndDate
+		if bookingEnd.After(endDate) {
+			bookingEnd = endDate
+		}
+		if !bookingStart.Before(bookingEnd) {
+			continue
+		}
+		changes = append(changes, change{at: bookingStart, units: bookedQuantity(&booking)}, change{at: bookingEnd, units: -bookedQuantity(&booking)})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].at.Equal(changes[j].at) {
+			return changes[i].units < changes[j].units
+		}
+		return changes[i].at.Before(changes[j].at)
+	})
+
+	booked, maxBooked := 0, 0
+	for _, change := range changes {
+		booked += change.units
+		if booked > maxBooked {
+			maxBooked = booked
+		}
+	}
+	return maxBooked
+}
+
+// bookedQuantity treats requests made before quantities existed as one unit.
+func bookedQuantity(rentRequest *RentRequest) int {
+	if rentRequest.Quantity > 0 {
+		return rentRequest.Quantity
+	}
+	return 1
+}
+
+// rejectOverlappingRequests rejects the requests still waiting for
+// confirmation or payment that no longer fit in the inventory of the post
+// once the given request is paid.
+func (service *RentService) rejectOverlappingRequests(paidRequest *RentRequest) error {
+	postDetail, err := service.serviceClient.GetPostByID(paidRequest.PostID)
+	if err != nil {
+		return err
+	}
+
+	states := []string{"waiting for confirmation", "Confirmed"}
+	for _, state := range states {
+		rentRequestList, err := service.repo.GetOvelappingRequest(paidRequest.PostID, state, paidRequest.StartDate, paidRequest.EndDate)
+		if err != nil {
+			return err
+		}
+
+		for _, overlappingRequest := range rentRequestList {
+			if overlappingRequest.ID == paidRequest.ID {
+				continue
+			}
+
+			err = service.checkAvailability(overlappingRequest.PostID, inventory(postDetail), bookedQuantity(&overlappingRequest), overlappingRequest.StartDate, overlappingRequest.EndDate, overlappingRequest.ID)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, ErrConflict) {
+				return err
+			}
+
+			overlappingRequest.Status = "Rejected"
+			overlappingRequest.UpdatedAt = time.Now()
+			err = service.updateRentRequest(&overlappingRequest, events.RentRequestRejected)
+			if err != nil {
+				return err
+			}
+
+			err = service.refreshBookingGroup(overlappingRequest.BookingGroupID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type BundleDto struct {
+	Items []RentDto `json:"items" validate:"required,min=2,dive"`
+}
+
+func (handler *RentHandler) CreateBundle(c echo.Context) error {
+	var bundle BundleDto
+
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err := c.Bind(&bundle); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(bundle); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	createdBundle, err := handler.service.CreateBundle(renterId, bundle)
+	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		} else if errors.Is(err, ErrWaitlistPriority) {
+			return c.JSON(http.StatusConflict, ErrWaitlistPriority.Error())
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		} else if errors.Is(err, ErrInvalidBundle) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidBundle.Error())
+		}
+		zap.L().Error("error creating bundle", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create bundle")
+	}
+
+	return c.JSON(http.StatusCreated, createdBundle)
+}
+
+func (handler *RentHandler) GetBundle(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	groupIdStr := c.Param("groupId")
+	if groupIdStr == "" {
+		zap.L().Error("missed groupId")
+		return echo.NewHTTPError(http.StatusBadRequest, "bundle ID is required")
+	}
+
+	bundle, err := handler.service.GetBundle(renterId, groupIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "bundle not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to retrieve bundle", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error getting bundle", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch bundle")
+	}
+
+	return c.JSON(http.StatusOK, bundle)
+}
+
+func (handler *RentHandler) PayBundle(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	groupIdStr := c.Param("groupId")
+	if groupIdStr == "" {
+		zap.L().Error("missed groupId")
+		return echo.NewHTTPError(http.StatusBadRequest, "bundle ID is required")
+	}
+
+	redirectURL, err := handler.service.PayBundle(renterId, groupIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "bundle not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to pay bundle", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error retrieving redirectURL", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve redirectURL")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"redirectURL": *redirectURL})
+}
+
+func (handler *RentHandler) UpdateBundlePaymentStatus(c echo.Context) error {
+	groupIdStr := c.QueryParam("groupId")
+	status := c.QueryParam("status")
+	if groupIdStr == "" || status == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "groupId and status are required")
+	}
+
+	message, err := handler.service.UpdateBundlePaymentStatus(groupIdStr, status)
+	if err != nil {
+		zap.L().Error("error updating bundle", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update bundle")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": *message})
+}
+
+func (handler *RentHandler) CancelBundle(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	groupIdStr := c.Param("groupId")
+	if groupIdStr == "" {
+		zap.L().Error("missed groupId")
+		return echo.NewHTTPError(http.StatusBadRequest, "bundle ID is required")
+	}
+
+	err := handler.service.CancelBundle(renterId, groupIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "bundle not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to cancel bundle", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error canceling bundle", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to cancel bundle")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the bundle has been canceled"})
+}
@@ -0,0 +1,66 @@
+package rent
+
+import (
+	"rental_service/events"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookingGroup bundles rent requests on several posts, possibly of different
+// owners, that are confirmed and paid together.
+type BookingGroup struct {
+	ID            uint
+	RenterID      uint
+	TotalPrice    int
+	Status        string
+	PaymentStatus string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type BundleRepository struct {
+	db *gorm.DB
+}
+
+func NewBundleRepository(db *gorm.DB) *BundleRepository {
+	return &BundleRepository{db: db}
+}
+
+// AddBundle stores the group and its rent requests, with their created
+// events, in one transaction so a bundle is never left half created.
+func (bundleRepo *BundleRepository) AddBundle(group *BookingGroup, rentRequests []*RentRequest) error {
+	return bundleRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&group).Error
+		if err != nil {
+			return err
+		}
+
+		for _, rentRequest := range rentRequests {
+			rentRequest.BookingGroupID = &group.ID
+			err = tx.Create(&rentRequest).Error
+			if err != nil {
+				return err
+			}
+
+			err = enqueueRentRequestEvent(tx, events.RentRequestCreated, rentRequest)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bundleRepo *BundleRepository) UpdateBundle(group *BookingGroup) error {
+	return bundleRepo.db.Save(&group).Error
+}
+
+func (bundleRepo *BundleRepository) GetBundleById(groupId uint) (*BookingGroup, error) {
+	var group BookingGroup
+	err := bundleRepo.db.First(&group, groupId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
@@ -0,0 +1,415 @@
+package rent
+
+import (
+	"errors"
+	"fmt"
+	"rental_service/events"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidBundle = errors.New("a bundle can contain each post only once")
+
+type OwnerSplit struct {
+	OwnerID uint `json:"owner_id"`
+	Amount  int  `json:"amount"`
+}
+
+type BundleResponse struct {
+	ID            uint                  `json:"id"`
+	TotalPrice    int                   `json:"total_price"`
+	Status        string                `json:"status"`
+	PaymentStatus string                `json:"payment_status"`
+	Items         []RentRequestResponse `json:"items"`
+	Splits        []OwnerSplit          `json:"splits"`
+}
+
+// CreateBundle books several posts at once. Every item is checked like a
+// single rent request and the bundle is only stored when all of them can be
+// booked. Each owner confirms their own items, and the bundle can be paid
+// once all of them are confirmed.
+func (service *RentService) CreateBundle(renterId uint, bundleDto BundleDto) (*BundleResponse, error) {
+	postIds := map[uint]bool{}
+	for _, item := range bundleDto.Items {
+		if postIds[item.PostId] {
+			return nil, ErrInvalidBundle
+		}
+		postIds[item.PostId] = true
+	}
+
+	var rentRequests []*RentRequest
+	var waitlistEntries []*WaitlistEntry
+	for _, item := range bundleDto.Items {
+		rentRequest, waitlistEntry, err := service.prepareRentRequest(renterId, item)
+		if err != nil {
+			return nil, err
+		}
+		rentRequests = append(rentRequests, rentRequest)
+		if waitlistEntry != nil {
+			waitlistEntries = append(waitlistEntries, waitlistEntry)
+		}
+	}
+
+	group := &BookingGroup{
+		RenterID:      renterId,
+		Status:        bundleStatus(rentRequests),
+		PaymentStatus: "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	for _, rentRequest := range rentRequests {
+		group.TotalPrice += rentRequest.TotalPrice
+	}
+
+	err := service.bundleRepo.AddBundle(group, rentRequests)
+	if err != nil {
+		return nil, err
+	}
+	for _, rentRequest := range rentRequests {
+		service.broadcastChange(events.RentRequestCreated, rentRequest)
+	}
+
+	for _, waitlistEntry := range waitlistEntries {
+		err = service.markWaitlistBooked(waitlistEntry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items := []RentRequest{}
+	for _, rentRequest := range rentRequests {
+		items = append(items, *rentRequest)
+	}
+	return newBundleResponse(group, items, nil), nil
+}
+
+func (service *RentService) GetBundle(renterId uint, groupIdStr string) (*BundleResponse, error) {
+	group, err := service.getBundle(groupIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.RenterID != renterId {
+		return nil, ErrNotAllowed
+	}
+
+	items, err := service.repo.GetGroupRentRequests(group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rentRequestIds []uint
+	for _, item := range items {
+		rentRequestIds = append(rentRequestIds, item.ID)
+	}
+	unreadCounts, err := service.messageRepo.CountUnreadMessages(renterId, rentRequestIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBundleResponse(group, items, unreadCounts), nil
+}
+
+// PayBundle asks the payment service for a single payment covering every
+// item of a confirmed bundle, along with the share of each owner.
+func (service *RentService) PayBundle(renterId uint, groupIdStr string) (*string, error) {
+	group, err := service.getBundle(groupIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.RenterID != renterId {
+		return nil, ErrNotAllowed
+	}
+
+	if group.Status != "Confirmed" {
+		return nil, ErrNotAllowed
+	}
+
+	items, err := service.repo.GetGroupRentRequests(group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentPayload := map[string]interface{}{
+		"bookingGroupId": group.ID,
+		"amount":         group.TotalPrice,
+		"splits":         ownerSplits(items),
+		"callbackURL":    fmt.Sprintf("%s/rent-request/bundle/callback?groupId=%v", service.config.CallbackBaseURL, group.ID),
+	}
+
+	return service.CreatePaymentRequest(paymentPayload)
+}
+
+// UpdateBundlePaymentStatus marks every item of the bundle as paid. When the
+// bundle was called off while the renter was paying, e.g. because one of the
+// posts got paid by someone else, the payment is refunded instead.
+func (service *RentService) UpdateBundlePaymentStatus(groupIdStr, status string) (*string, error) {
+	group, err := service.getBundle(groupIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.PaymentStatus == "success" || group.PaymentStatus == "refunded" {
+		return nil, ErrNotAllowed
+	}
+
+	if status != "success" && status != "cancel" {
+		return nil, fmt.Errorf("invalid payment status %q", status)
+	}
+
+	group.PaymentStatus = status
+	group.UpdatedAt = time.Now()
+
+	if status == "cancel" {
+		err = service.bundleRepo.UpdateBundle(group)
+		if err != nil {
+			return nil, err
+		}
+		message := "Your payment has been canceled"
+		return &message, nil
+	}
+
+	if group.Status != "Confirmed" {
+		refundPayload := map[string]interface{}{
+			"bookingGroupId": group.ID,
+			"amount":         group.TotalPrice,
+		}
+		err = service.RefundPayment(refundPayload)
+		if err != nil {
+			return nil, err
+		}
+		group.PaymentStatus = "refunded"
+		err = service.bundleRepo.UpdateBundle(group)
+		if err != nil {
+			return nil, err
+		}
+		message := "The bundle is no longer available, your payment has been refunded"
+		return &message, nil
+	}
+
+	group.Status = "paid"
+	err = service.bundleRepo.UpdateBundle(group)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := service.repo.GetGroupRentRequests(group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].Status = "paid"
+		items[i].PaymentStatus = "success"
+		items[i].UpdatedAt = time.Now()
+		err = service.updateRentRequest(&items[i], events.RentRequestPaid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range items {
+		err = service.rejectOverlappingRequests(&items[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	message := "Your payment was processed successfully!"
+	return &message, nil
+}
+
+// CancelBundle cancels every item of the bundle. A paid bundle is refunded
+// the price of the items that are still booked.
+func (service *RentService) CancelBundle(renterId uint, groupIdStr string) error {
+	group, err := service.getBundle(groupIdStr)
+	if err != nil {
+		return err
+	}
+
+	if group.RenterID != renterId {
+		return ErrNotAllowed
+	}
+
+	if group.Status == "canceled" {
+		return ErrNotAllowed
+	}
+
+	items, err := service.repo.GetGroupRentRequests(group.ID)
+	if err != nil {
+		return err
+	}
+
+	if group.Status == "paid" {
+		refundAmount := 0
+		for _, item := range items {
+			if item.Status == "paid" {
+				refundAmount += item.TotalPrice
+			}
+		}
+
+		if refundAmount > 0 {
+			refundPayload := map[string]interface{}{
+				"bookingGroupId": group.ID,
+				"amount":         refundAmount,
+			}
+			err = service.RefundPayment(refundPayload)
+			if err != nil {
+				return err
+			}
+			group.PaymentStatus = "refunded"
+		}
+	}
+
+	group.Status = "canceled"
+	group.UpdatedAt = time.Now()
+	err = service.bundleRepo.UpdateBundle(group)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		item := &items[i]
+		wasPaid := item.Status == "paid"
+		if !wasPaid && item.Status != "Confirmed" && item.Status != "waiting for confirmation" {
+			continue
+		}
+
+		item.Status = "canceled"
+		if wasPaid {
+			item.PaymentStatus = "refunded"
+		}
+		item.UpdatedAt = time.Now()
+		err = service.updateRentRequest(item, events.RentRequestCancelled)
+		if err != nil {
+			return err
+		}
+
+		service.cancelOpenModifications(item.ID)
+		if wasPaid {
+			service.releaseWaitlist(item.PostID, item.StartDate, item.EndDate)
+		}
+	}
+	return nil
+}
+
+// refreshBookingGroup keeps a bundle that is not paid yet in line with its
+// items: it is confirmed once every owner confirmed, and called off as a
+// whole as soon as one item is rejected or canceled.
+func (service *RentService) refreshBookingGroup(groupId *uint) error {
+	if groupId == nil {
+		return nil
+	}
+
+	group, err := service.bundleRepo.GetBundleById(*groupId)
+	if err != nil {
+		return err
+	}
+
+	if group.Status == "paid" || group.Status == "canceled" {
+		return nil
+	}
+
+	items, err := service.repo.GetGroupRentRequests(group.ID)
+	if err != nil {
+		return err
+	}
+
+	calledOff := false
+	for _, item := range items {
+		if item.Status == "Rejected" || item.Status == "canceled" {
+			calledOff = true
+		}
+	}
+
+	if calledOff {
+		for i := range items {
+			item := &items[i]
+			if item.Status != "Confirmed" && item.Status != "waiting for confirmation" {
+				continue
+			}
+			item.Status = "canceled"
+			item.UpdatedAt = time.Now()
+			err = service.updateRentRequest(item, events.RentRequestCancelled)
+			if err != nil {
+				return err
+			}
+		}
+		group.Status = "canceled"
+	} else {
+		itemPointers := []*RentRequest{}
+		for i := range items {
+			itemPointers = append(itemPointers, &items[i])
+		}
+		group.Status = bundleStatus(itemPointers)
+	}
+
+	group.TotalPrice = 0
+	for _, item := range items {
+		group.TotalPrice += item.TotalPrice
+	}
+	group.UpdatedAt = time.Now()
+	return service.bundleRepo.UpdateBundle(group)
+}
+
+func (service *RentService) getBundle(groupIdStr string) (*BookingGroup, error) {
+	groupId, err := strconv.ParseUint(groupIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := service.bundleRepo.GetBundleById(uint(groupId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return group, nil
+}
+
+// bundleStatus is "Confirmed" once every item is confirmed.
+func bundleStatus(rentRequests []*RentRequest) string {
+	for _, rentRequest := range rentRequests {
+		if rentRequest.Status != "Confirmed" {
+			return "waiting for confirmation"
+		}
+	}
+	return "Confirmed"
+}
+
+// ownerSplits sums the price of the items per owner, ordered by owner ID.
+func ownerSplits(items []RentRequest) []OwnerSplit {
+	amounts := map[uint]int{}
+	for _, item := range items {
+		amounts[item.OwnerID] += item.TotalPrice
+	}
+
+	splits := []OwnerSplit{}
+	for ownerId, amount := range amounts {
+		splits = append(splits, OwnerSplit{OwnerID: ownerId, Amount: amount})
+	}
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].OwnerID < splits[j].OwnerID
+	})
+	return splits
+}
+
+func newBundleResponse(group *BookingGroup, items []RentRequest, unreadCounts map[uint]int64) *BundleResponse {
+	bundleResponse := &BundleResponse{
+		ID:            group.ID,
+		TotalPrice:    group.TotalPrice,
+		Status:        group.Status,
+		PaymentStatus: group.PaymentStatus,
+		Items:         []RentRequestResponse{},
+		Splits:        ownerSplits(items),
+	}
+	for i := range items {
+		bundleResponse.Items = append(bundleResponse.Items, newRentRequestResponse(&items[i], unreadCounts[items[i].ID]))
+	}
+	return bundleResponse
+}
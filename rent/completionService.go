@@ -0,0 +1,46 @@
+package rent
+
+import (
+	"context"
+	"rental_service/events"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CompleteFinishedRentRequests marks paid bookings whose period is over as
+// completed.
+func (service *RentService) CompleteFinishedRentRequests() error {
+	rentRequestList, err := service.repo.GetFinishedRentRequests("paid", time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range rentRequestList {
+		rentRequest := &rentRequestList[i]
+		rentRequest.Status = "completed"
+		rentRequest.UpdatedAt = time.Now()
+		err = service.updateRentRequest(rentRequest, events.RentRequestCompleted)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunRentRequestCompletion completes finished bookings periodically.
+func (service *RentService) RunRentRequestCompletion(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := service.CompleteFinishedRentRequests(); err != nil {
+				zap.L().Error("error completing finished rent requests", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package rent
+
+import (
+	"rental_service/events"
+	"rental_service/metrics"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RentRequestEvent is the payload of the rent request domain events.
+type RentRequestEvent struct {
+	RentRequestID   uint      `json:"rentRequestId"`
+	RenterID        uint      `json:"renterId"`
+	OwnerID         uint      `json:"ownerId"`
+	PostID          uint      `json:"postId"`
+	ParentRequestID *uint     `json:"parentRequestId,omitempty"`
+	BookingGroupID  *uint     `json:"bookingGroupId,omitempty"`
+	StartDate       time.Time `json:"startDate"`
+	EndDate         time.Time `json:"endDate"`
+	Quantity        int       `json:"quantity"`
+	TotalPrice      int       `json:"totalPrice"`
+	Status          string    `json:"status"`
+	PaymentStatus   string    `json:"paymentStatus"`
+	OccurredAt      time.Time `json:"occurredAt"`
+}
+
+// Broadcaster pushes changes to the users connected in real time.
+type Broadcaster interface {
+	Broadcast(userIds []uint, eventType string, data interface{})
+}
+
+func enqueueRentRequestEvent(tx *gorm.DB, eventType string, rentRequest *RentRequest) error {
+	return events.Enqueue(tx, eventType, rentRequest.ID, newRentRequestEvent(rentRequest))
+}
+
+func newRentRequestEvent(rentRequest *RentRequest) RentRequestEvent {
+	return RentRequestEvent{
+		RentRequestID:   rentRequest.ID,
+		RenterID:        rentRequest.RenterID,
+		OwnerID:         rentRequest.OwnerID,
+		PostID:          rentRequest.PostID,
+		ParentRequestID: rentRequest.ParentRequestID,
+		BookingGroupID:  rentRequest.BookingGroupID,
+		StartDate:       rentRequest.StartDate,
+		EndDate:         rentRequest.EndDate,
+		Quantity:        bookedQuantity(rentRequest),
+		TotalPrice:      rentRequest.TotalPrice,
+		Status:          rentRequest.Status,
+		PaymentStatus:   rentRequest.PaymentStatus,
+		OccurredAt:      time.Now(),
+	}
+}
+
+// addRentRequest stores a new rent request with its domain event and lets its
+// renter and owner know right away.
+func (service *RentService) addRentRequest(rentRequest *RentRequest, eventType string) error {
+	err := service.repo.AddRentRequestWithEvent(rentRequest, eventType)
+	if err != nil {
+		return err
+	}
+	service.broadcastChange(eventType, rentRequest)
+	return nil
+}
+
+// updateRentRequest is addRentRequest for changes of existing requests.
+func (service *RentService) updateRentRequest(rentRequest *RentRequest, eventType string) error {
+	err := service.repo.UpdateRentRequestWithEvent(rentRequest, eventType)
+	if err != nil {
+		return err
+	}
+	service.broadcastChange(eventType, rentRequest)
+	return nil
+}
+
+// broadcastChange follows every committed change, which it also counts.
+func (service *RentService) broadcastChange(eventType string, rentRequest *RentRequest) {
+	metrics.CountRentRequestEvent(eventType)
+	// Instant bookings are created confirmed, without a RentRequestConfirmed.
+	if eventType == events.RentRequestCreated && rentRequest.Status == "Confirmed" {
+		metrics.CountRentRequestEvent(events.RentRequestConfirmed)
+	}
+	service.broadcaster.Broadcast([]uint{rentRequest.RenterID, rentRequest.OwnerID}, eventType, newRentRequestEvent(rentRequest))
+}
@@ -0,0 +1,58 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ExtensionDto.EndDate follows the same local format as RentDto.
+type ExtensionDto struct {
+	EndDate string `json:"endDate" validate:"required"`
+}
+
+func (handler *RentHandler) ExtendRentRequest(c echo.Context) error {
+	var extension ExtensionDto
+
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	if err := c.Bind(&extension); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(extension); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	extensionRequestId, err := handler.service.ExtendRentRequest(renterId, rentRequestIdStr, extension)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to extend rent request", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		} else if errors.Is(err, ErrConflict) {
+			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		}
+		zap.L().Error("error extending rent request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to extend rent request")
+	}
+
+	return c.JSON(http.StatusCreated, extensionRequestId)
+}
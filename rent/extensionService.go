@@ -0,0 +1,68 @@
+package rent
+
+import (
+	"fmt"
+	"rental_service/events"
+	"time"
+)
+
+// ExtendRentRequest creates a new rent request, linked to a paid booking that
+// has not finished yet, for the period right after its end date. The
+// extension goes through the usual confirmation and payment flow on its own.
+func (service *RentService) ExtendRentRequest(renterId uint, rentRequestIdStr string, extensionDto ExtensionDto) (*uint, error) {
+	parentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentRequest.RenterID != renterId {
+		return nil, ErrNotAllowed
+	}
+
+	if parentRequest.Status != "paid" || !time.Now().Before(parentRequest.EndDate) {
+		return nil, ErrNotAllowed
+	}
+
+	postDetail, err := service.serviceClient.GetPostByID(parentRequest.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate := parentRequest.EndDate
+	endDate, err := resolveEndDate(postDetail, extensionDto.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if !startDate.Before(endDate) {
+		return nil, fmt.Errorf("invalid date")
+	}
+
+	err = service.checkAvailability(parentRequest.PostID, inventory(postDetail), bookedQuantity(parentRequest), startDate, endDate, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPrice, err := calculateTotalPrice(postDetail, bookedQuantity(parentRequest), startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	extensionRequest := &RentRequest{
+		RenterID:        parentRequest.RenterID,
+		OwnerID:         parentRequest.OwnerID,
+		PostID:          parentRequest.PostID,
+		ParentRequestID: &parentRequest.ID,
+		StartDate:       startDate,
+		EndDate:         endDate,
+		Quantity:        bookedQuantity(parentRequest),
+		TotalPrice:      totalPrice,
+		Status:          "waiting for confirmation",
+		PaymentStatus:   "pending",
+		CreatedAt:       time.Now(),
+	}
+	err = service.addRentRequest(extensionRequest, events.RentRequestCreated)
+	if err != nil {
+		return nil, err
+	}
+	return &extensionRequest.ID, nil
+}
@@ -0,0 +1,70 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type InstantBookDto struct {
+	Enabled             bool    `json:"enabled"`
+	RequireVerified     bool    `json:"requireVerified"`
+	MinRating           float64 `json:"minRating" validate:"min=0,max=5"`
+	NoPastCancellations bool    `json:"noPastCancellations"`
+}
+
+func (handler *RentHandler) UpdateInstantBookSetting(c echo.Context) error {
+	var instantBook InstantBookDto
+
+	ownerId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	postIdStr := c.Param("postId")
+	if postIdStr == "" {
+		zap.L().Error("missed postId")
+		return echo.NewHTTPError(http.StatusBadRequest, "post ID is required")
+	}
+
+	if err := c.Bind(&instantBook); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(instantBook); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	setting, err := handler.service.UpdateInstantBookSetting(ownerId, postIdStr, instantBook)
+	if err != nil {
+		if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to change instant booking", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error updating instant booking", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update instant booking")
+	}
+
+	return c.JSON(http.StatusOK, setting)
+}
+
+func (handler *RentHandler) GetInstantBookSetting(c echo.Context) error {
+	postIdStr := c.Param("postId")
+	if postIdStr == "" {
+		zap.L().Error("missed postId")
+		return echo.NewHTTPError(http.StatusBadRequest, "post ID is required")
+	}
+
+	setting, err := handler.service.GetInstantBookSetting(postIdStr)
+	if err != nil {
+		zap.L().Error("error getting instant booking", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch instant booking")
+	}
+
+	return c.JSON(http.StatusOK, setting)
+}
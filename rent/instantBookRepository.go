@@ -0,0 +1,38 @@
+package rent
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InstantBookSetting struct {
+	PostID              uint `gorm:"primaryKey;autoIncrement:false"`
+	OwnerID             uint
+	Enabled             bool
+	RequireVerified     bool
+	MinRating           float64
+	NoPastCancellations bool
+	UpdatedAt           time.Time
+}
+
+type InstantBookRepository struct {
+	db *gorm.DB
+}
+
+func NewInstantBookRepository(db *gorm.DB) *InstantBookRepository {
+	return &InstantBookRepository{db: db}
+}
+
+func (instantBookRepo *InstantBookRepository) SaveSetting(setting *InstantBookSetting) error {
+	return instantBookRepo.db.Save(&setting).Error
+}
+
+func (instantBookRepo *InstantBookRepository) GetSetting(postId uint) (*InstantBookSetting, error) {
+	var setting InstantBookSetting
+	err := instantBookRepo.db.First(&setting, postId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
@@ -0,0 +1,126 @@
+package rent
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InstantBookResponse struct {
+	PostID              uint    `json:"post_id"`
+	Enabled             bool    `json:"enabled"`
+	RequireVerified     bool    `json:"require_verified"`
+	MinRating           float64 `json:"min_rating"`
+	NoPastCancellations bool    `json:"no_past_cancellations"`
+}
+
+func (service *RentService) UpdateInstantBookSetting(ownerId uint, postIdStr string, instantBookDto InstantBookDto) (*InstantBookResponse, error) {
+	postId, err := strconv.ParseUint(postIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	postDetail, err := service.serviceClient.GetPostByID(uint(postId))
+	if err != nil {
+		return nil, err
+	}
+
+	if postDetail.OwnerId != ownerId {
+		return nil, ErrNotAllowed
+	}
+
+	setting := &InstantBookSetting{
+		PostID:              uint(postId),
+		OwnerID:             ownerId,
+		Enabled:             instantBookDto.Enabled,
+		RequireVerified:     instantBookDto.RequireVerified,
+		MinRating:           instantBookDto.MinRating,
+		NoPastCancellations: instantBookDto.NoPastCancellations,
+		UpdatedAt:           time.Now(),
+	}
+	err = service.instantBookRepo.SaveSetting(setting)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInstantBookResponse(setting), nil
+}
+
+func (service *RentService) GetInstantBookSetting(postIdStr string) (*InstantBookResponse, error) {
+	postId, err := strconv.ParseUint(postIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	setting, err := service.instantBookRepo.GetSetting(uint(postId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &InstantBookResponse{PostID: uint(postId)}, nil
+		}
+		return nil, err
+	}
+
+	return newInstantBookResponse(setting), nil
+}
+
+// canInstantBook reports whether a new request from the renter may skip the
+// owner's confirmation because the post has instant booking enabled and the
+// renter meets all of its criteria.
+func (service *RentService) canInstantBook(renterId, postId uint) (bool, error) {
+	setting, err := service.instantBookRepo.GetSetting(postId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !setting.Enabled {
+		return false, nil
+	}
+
+	if setting.RequireVerified || setting.MinRating > 0 {
+		renterDetail, err := service.serviceClient.GetUserByID(renterId)
+		if err != nil {
+			return false, err
+		}
+		if setting.RequireVerified && !renterDetail.Verified {
+			return false, nil
+		}
+		if renterDetail.Rating < setting.MinRating {
+			return false, nil
+		}
+	}
+
+	if setting.NoPastCancellations {
+		cancellations, err := service.repo.CountRenterRentRequests(renterId, "canceled")
+		if err != nil {
+			return false, err
+		}
+		if cancellations > 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func newInstantBookResponse(setting *InstantBookSetting) *InstantBookResponse {
+	return &InstantBookResponse{
+		PostID:              setting.PostID,
+		Enabled:             setting.Enabled,
+		RequireVerified:     setting.RequireVerified,
+		MinRating:           setting.MinRating,
+		NoPastCancellations: setting.NoPastCancellations,
+	}
+}
+
+type UserResponse struct {
+	Verified bool    `json:"verified"`
+	Rating   float64 `json:"rating"`
+	Email    string  `json:"email"`
+	Phone    string  `json:"phone"`
+	Locale   string  `json:"locale"`
+}
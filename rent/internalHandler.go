@@ -0,0 +1,24 @@
+package rent
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func (handler *RentHandler) GetPostBookings(c echo.Context) error {
+	postIdStr := c.Param("postId")
+	if postIdStr == "" {
+		zap.L().Error("missed postId")
+		return echo.NewHTTPError(http.StatusBadRequest, "post ID is required")
+	}
+
+	bookings, err := handler.service.GetPostBookings(postIdStr)
+	if err != nil {
+		zap.L().Error("error getting post bookings", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch bookings")
+	}
+
+	return c.JSON(http.StatusOK, bookings)
+}
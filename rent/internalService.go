@@ -0,0 +1,27 @@
+package rent
+
+import (
+	"strconv"
+	"time"
+)
+
+// GetPostBookings returns the confirmed and paid requests of a post which are
+// not over yet, so the post service can tell whether a post may be edited or
+// removed.
+func (service *RentService) GetPostBookings(postIdStr string) ([]AdminRentRequestResponse, error) {
+	postId, err := strconv.ParseUint(postIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	rents, err := service.repo.GetUpcomingPostRentRequests(uint(postId), []string{"Confirmed", "paid"}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	bookings := []AdminRentRequestResponse{}
+	for i := range rents {
+		bookings = append(bookings, newAdminRentRequestResponse(&rents[i], nil))
+	}
+	return bookings, nil
+}
@@ -0,0 +1,94 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type MessageHandler struct {
+	service *MessageService
+
+	validate *validator.Validate
+}
+
+func NewMessageHandler(service *MessageService, validate *validator.Validate) *MessageHandler {
+	return &MessageHandler{service: service, validate: validate}
+}
+
+type MessageDto struct {
+	Body string `json:"body" validate:"required,max=2000"`
+}
+
+func (handler *MessageHandler) SendMessage(c echo.Context) error {
+	var message MessageDto
+
+	senderId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	if err := c.Bind(&message); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(message); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	createdMessage, err := handler.service.SendMessage(senderId, rentRequestIdStr, message)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to post in thread", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error sending message", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to send message")
+	}
+
+	return c.JSON(http.StatusCreated, createdMessage)
+}
+
+func (handler *MessageHandler) GetMessages(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	pageStr := c.QueryParam("page")
+
+	messages, err := handler.service.GetMessages(userId, rentRequestIdStr, pageStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to read thread", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error getting messages", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch messages")
+	}
+
+	return c.JSON(http.StatusOK, messages)
+}
@@ -0,0 +1,64 @@
+package rent
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Message struct {
+	ID            uint
+	RentRequestID uint
+	SenderID      uint
+	Body          string
+	ReadAt        *time.Time
+	CreatedAt     time.Time
+}
+
+type MessageRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageRepository(db *gorm.DB) *MessageRepository {
+	return &MessageRepository{db: db}
+}
+
+func (messageRepo *MessageRepository) AddMessage(message *Message) error {
+	return messageRepo.db.Create(&message).Error
+}
+
+func (messageRepo *MessageRepository) GetMessages(rentRequestId uint, offset, limit int) ([]Message, error) {
+	var messageList []Message
+	err := messageRepo.db.Model(&Message{}).Where("rent_request_id = ?", rentRequestId).Order("created_at desc, id desc").Offset(offset).Limit(limit).Find(&messageList).Error
+	return messageList, err
+}
+
+// MarkMessagesAsRead marks the given messages of the thread read, leaving the
+// ones the reader has not fetched yet unread.
+func (messageRepo *MessageRepository) MarkMessagesAsRead(rentRequestId, readerId uint, messageIds []uint, readAt time.Time) error {
+	if len(messageIds) == 0 {
+		return nil
+	}
+	return messageRepo.db.Model(&Message{}).Where("rent_request_id = ? and id in ? and sender_id <> ? and read_at is null", rentRequestId, messageIds, readerId).Update("read_at", readAt).Error
+}
+
+func (messageRepo *MessageRepository) CountUnreadMessages(readerId uint, rentRequestIds []uint) (map[uint]int64, error) {
+	unreadCounts := make(map[uint]int64)
+	if len(rentRequestIds) == 0 {
+		return unreadCounts, nil
+	}
+
+	var rows []struct {
+		RentRequestID uint
+		Count         int64
+	}
+	err := messageRepo.db.Model(&Message{}).Select("rent_request_id, count(*) as count").Where("rent_request_id in ? and sender_id <> ? and read_at is null", rentRequestIds, readerId).Group("rent_request_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		unreadCounts[row.RentRequestID] = row.Count
+	}
+	return unreadCounts, nil
+}
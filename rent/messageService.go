@@ -0,0 +1,135 @@
+package rent
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MessageService struct {
+	repo     *MessageRepository
+	rentRepo *RentRepository
+
+	broadcaster Broadcaster
+}
+
+func NewMessageService(repo *MessageRepository, rentRepo *RentRepository, broadcaster Broadcaster) *MessageService {
+	return &MessageService{repo: repo, rentRepo: rentRepo, broadcaster: broadcaster}
+}
+
+type MessageResponse struct {
+	ID        uint       `json:"id"`
+	SenderID  uint       `json:"sender_id"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// getThreadRentRequest loads the rent request a thread belongs to and makes
+// sure the caller is either its renter or its owner.
+func (service *MessageService) getThreadRentRequest(userId uint, rentRequestIdStr string) (*RentRequest, error) {
+	rentRequestId, err := strconv.ParseUint(rentRequestIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	rentRequest, err := service.rentRepo.GetRentRequestsById(uint(rentRequestId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	if userId != rentRequest.RenterID && userId != rentRequest.OwnerID {
+		return nil, ErrNotAllowed
+	}
+
+	return rentRequest, nil
+}
+
+func (service *MessageService) SendMessage(senderId uint, rentRequestIdStr string, messageDto MessageDto) (*MessageResponse, error) {
+	rentRequest, err := service.getThreadRentRequest(senderId, rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		RentRequestID: rentRequest.ID,
+		SenderID:      senderId,
+		Body:          messageDto.Body,
+		CreatedAt:     time.Now(),
+	}
+	err = service.repo.AddMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	messageResponse := &MessageResponse{
+		ID:        message.ID,
+		SenderID:  message.SenderID,
+		Body:      message.Body,
+		CreatedAt: message.CreatedAt,
+	}
+	service.broadcaster.Broadcast([]uint{rentRequest.RenterID, rentRequest.OwnerID}, "MessageSent", map[string]interface{}{
+		"rentRequestId": rentRequest.ID,
+		"message":       messageResponse,
+	})
+	return messageResponse, nil
+}
+
+// GetThreadParticipants returns the renter and the owner of the rent request
+// when the caller is one of them.
+func (service *MessageService) GetThreadParticipants(userId uint, rentRequestIdStr string) ([]uint, error) {
+	rentRequest, err := service.getThreadRentRequest(userId, rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+	return []uint{rentRequest.RenterID, rentRequest.OwnerID}, nil
+}
+
+// GetMessages returns a page of the thread, newest first, and marks the
+// messages sent by the other participant as read.
+func (service *MessageService) GetMessages(userId uint, rentRequestIdStr, pageStr string) ([]MessageResponse, error) {
+	rentRequest, err := service.getThreadRentRequest(userId, rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	size := 20
+	offset := (page - 1) * size
+	messages, err := service.repo.GetMessages(rentRequest.ID, offset, size)
+	if err != nil {
+		return nil, err
+	}
+
+	messageIds := make([]uint, 0, len(messages))
+	for _, message := range messages {
+		messageIds = append(messageIds, message.ID)
+	}
+	readAt := time.Now()
+	err = service.repo.MarkMessagesAsRead(rentRequest.ID, userId, messageIds, readAt)
+	if err != nil {
+		return nil, err
+	}
+
+	messageResponseList := []MessageResponse{}
+	for _, message := range messages {
+		messageResponseList = append(messageResponseList, MessageResponse{
+			ID:        message.ID,
+			SenderID:  message.SenderID,
+			Body:      message.Body,
+			CreatedAt: message.CreatedAt,
+			ReadAt:    message.ReadAt,
+		})
+	}
+
+	return messageResponseList, nil
+}
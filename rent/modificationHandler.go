@@ -0,0 +1,196 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ModificationDto dates follow the same local format as RentDto.
+type ModificationDto struct {
+	StartDate string `json:"startDate" validate:"required"`
+	EndDate   string `json:"endDate" validate:"required"`
+}
+
+func (handler *RentHandler) ProposeModification(c echo.Context) error {
+	var modification ModificationDto
+
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	if err := c.Bind(&modification); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(modification); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	createdModification, err := handler.service.ProposeModification(renterId, rentRequestIdStr, modification)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to modify rent request", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		} else if errors.Is(err, ErrOpenModification) {
+			return echo.NewHTTPError(http.StatusConflict, ErrOpenModification.Error())
+		} else if errors.Is(err, ErrConflict) {
+			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		}
+		zap.L().Error("error proposing modification", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to propose modification")
+	}
+
+	return c.JSON(http.StatusCreated, createdModification)
+}
+
+func (handler *RentHandler) GetModifications(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	modifications, err := handler.service.GetModifications(userId, rentRequestIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to retrieve modifications", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error getting modifications", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch modifications")
+	}
+
+	return c.JSON(http.StatusOK, modifications)
+}
+
+func (handler *RentHandler) ApproveModification(c echo.Context) error {
+	ownerId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	modificationIdStr := c.Param("modificationId")
+	if rentRequestIdStr == "" || modificationIdStr == "" {
+		zap.L().Error("missed rentRequestId or modificationId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID and modification ID are required")
+	}
+
+	modification, err := handler.service.ApproveModification(ownerId, rentRequestIdStr, modificationIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "modification not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to approve modification", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		} else if errors.Is(err, ErrConflict) {
+			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		}
+		zap.L().Error("error approving modification", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to approve modification")
+	}
+
+	return c.JSON(http.StatusOK, modification)
+}
+
+func (handler *RentHandler) RejectModification(c echo.Context) error {
+	ownerId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	modificationIdStr := c.Param("modificationId")
+	if rentRequestIdStr == "" || modificationIdStr == "" {
+		zap.L().Error("missed rentRequestId or modificationId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID and modification ID are required")
+	}
+
+	err := handler.service.RejectModification(ownerId, rentRequestIdStr, modificationIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "modification not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to reject modification", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error rejecting modification", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to reject modification")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the modification has been rejected"})
+}
+
+func (handler *RentHandler) PayModification(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	modificationIdStr := c.Param("modificationId")
+	if rentRequestIdStr == "" || modificationIdStr == "" {
+		zap.L().Error("missed rentRequestId or modificationId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID and modification ID are required")
+	}
+
+	redirectURL, err := handler.service.PayModification(renterId, rentRequestIdStr, modificationIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "modification not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to pay modification", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error retrieving redirectURL", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve redirectURL")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"redirectURL": *redirectURL})
+}
+
+func (handler *RentHandler) UpdateModificationPaymentStatus(c echo.Context) error {
+	modificationIdStr := c.QueryParam("modificationId")
+	status := c.QueryParam("status")
+	if modificationIdStr == "" || status == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "modificationId and status are required")
+	}
+
+	message, err := handler.service.UpdateModificationPaymentStatus(modificationIdStr, status)
+	if err != nil {
+		zap.L().Error("error updating modification", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update modification")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": *message})
+}
@@ -0,0 +1,82 @@
+package rent
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RentModification struct {
+	ID              uint
+	RentRequestID   uint
+	StartDate       time.Time
+	EndDate         time.Time
+	TotalPrice      int
+	PriceDifference int
+	Status          string
+	PaymentStatus   string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+type ModificationRepository struct {
+	db *gorm.DB
+}
+
+func NewModificationRepository(db *gorm.DB) *ModificationRepository {
+	return &ModificationRepository{db: db}
+}
+
+func (modificationRepo *ModificationRepository) AddModification(modification *RentModification) error {
+	return modificationRepo.db.Create(&modification).Error
+}
+
+func (modificationRepo *ModificationRepository) UpdateModification(modification *RentModification) error {
+	return modificationRepo.db.Save(&modification).Error
+}
+
+// ApplyModificationWithEvent saves an applied modification along with the
+// new dates of its rent request and the domain event, so neither is saved
+// without the other.
+func (modificationRepo *ModificationRepository) ApplyModificationWithEvent(modification *RentModification, rentRequest *RentRequest, eventType string) error {
+	return modificationRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(&modification).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Save(&rentRequest).Error
+		if err != nil {
+			return err
+		}
+		return enqueueRentRequestEvent(tx, eventType, rentRequest)
+	})
+}
+
+func (modificationRepo *ModificationRepository) GetModificationById(modificationId uint) (*RentModification, error) {
+	var modification RentModification
+	err := modificationRepo.db.First(&modification, modificationId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &modification, nil
+}
+
+func (modificationRepo *ModificationRepository) GetModifications(rentRequestId uint) ([]RentModification, error) {
+	var modificationList []RentModification
+	err := modificationRepo.db.Model(&RentModification{}).Where("rent_request_id = ?", rentRequestId).Order("created_at desc, id desc").Find(&modificationList).Error
+	return modificationList, err
+}
+
+// CountOpenModifications counts the modifications of a rent request that are
+// still waiting for the owner or for a payment.
+func (modificationRepo *ModificationRepository) CountOpenModifications(rentRequestId uint) (int64, error) {
+	var count int64
+	err := modificationRepo.db.Model(&RentModification{}).Where("rent_request_id = ? and status in ?", rentRequestId, []string{"pending", "awaiting payment"}).Count(&count).Error
+	return count, err
+}
+
+// CancelOpenModifications cancels the modifications of a rent request that
+// are still waiting for the owner or for a payment.
+func (modificationRepo *ModificationRepository) CancelOpenModifications(rentRequestId uint) error {
+	return modificationRepo.db.Model(&RentModification{}).Where("rent_request_id = ? and status in ?", rentRequestId, []string{"pending", "awaiting payment"}).Updates(map[string]interface{}{"status": "canceled", "updated_at": time.Now()}).Error
+}
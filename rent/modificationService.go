@@ -0,0 +1,416 @@
+package rent
+
+import (
+	"errors"
+	"fmt"
+	"rental_service/events"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrOpenModification = errors.New("there is already an open modification for this rent request")
+
+type ModificationResponse struct {
+	ID              uint      `json:"id"`
+	StartDate       time.Time `json:"start_date"`
+	EndDate         time.Time `json:"end_date"`
+	TotalPrice      int       `json:"total_price"`
+	PriceDifference int       `json:"price_difference"`
+	Status          string    `json:"status"`
+	PaymentStatus   string    `json:"payment_status"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func isModifiable(rentRequest *RentRequest) bool {
+	return rentRequest.Status == "waiting for confirmation" || rentRequest.Status == "Confirmed" || rentRequest.Status == "paid"
+}
+
+// ProposeModification records new dates requested by the renter. The request
+// keeps its current status until the owner approves the change.
+func (service *RentService) ProposeModification(renterId uint, rentRequestIdStr string, modificationDto ModificationDto) (*ModificationResponse, error) {
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if rentRequest.RenterID != renterId {
+		return nil, ErrNotAllowed
+	}
+
+	if !isModifiable(rentRequest) {
+		return nil, ErrNotAllowed
+	}
+
+	postDetail, err := service.serviceClient.GetPostByID(rentRequest.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, endDate, err := resolvePeriod(postDetail, modificationDto.StartDate, modificationDto.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	openModifications, err := service.modificationRepo.CountOpenModifications(rentRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+	if openModifications > 0 {
+		return nil, ErrOpenModification
+	}
+
+	modification := &RentModification{
+		RentRequestID: rentRequest.ID,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	err = service.priceModification(rentRequest, modification)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.modificationRepo.AddModification(modification)
+	if err != nil {
+		return nil, err
+	}
+
+	return newModificationResponse(modification), nil
+}
+
+func (service *RentService) GetModifications(userId uint, rentRequestIdStr string) ([]ModificationResponse, error) {
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId != rentRequest.RenterID && userId != rentRequest.OwnerID {
+		return nil, ErrNotAllowed
+	}
+
+	modifications, err := service.modificationRepo.GetModifications(rentRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	modificationResponseList := []ModificationResponse{}
+	for _, modification := range modifications {
+		modificationResponseList = append(modificationResponseList, *newModificationResponse(&modification))
+	}
+	return modificationResponseList, nil
+}
+
+// ApproveModification applies the new dates right away unless the booking is
+// already paid and costs more, in which case the renter has to pay the
+// difference first. A cheaper paid booking is refunded the difference once
+// the new dates are saved; a refund that fails stays "refund pending" on the
+// modification rather than being sent twice.
+func (service *RentService) ApproveModification(ownerId uint, rentRequestIdStr, modificationIdStr string) (*ModificationResponse, error) {
+	rentRequest, modification, err := service.getModification(rentRequestIdStr, modificationIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if rentRequest.OwnerID != ownerId {
+		return nil, ErrNotAllowed
+	}
+
+	if modification.Status != "pending" || !isModifiable(rentRequest) {
+		return nil, ErrNotAllowed
+	}
+
+	err = service.priceModification(rentRequest, modification)
+	if err != nil {
+		return nil, err
+	}
+
+	modification.UpdatedAt = time.Now()
+
+	if rentRequest.Status == "paid" && modification.PriceDifference > 0 {
+		modification.Status = "awaiting payment"
+		modification.PaymentStatus = "pending"
+		err = service.modificationRepo.UpdateModification(modification)
+		if err != nil {
+			return nil, err
+		}
+		return newModificationResponse(modification), nil
+	}
+
+	refund := rentRequest.Status == "paid" && modification.PriceDifference < 0
+	if refund {
+		modification.PaymentStatus = "refund pending"
+	}
+
+	err = service.applyModification(rentRequest, modification)
+	if err != nil {
+		return nil, err
+	}
+
+	if refund {
+		refundPayload := map[string]interface{}{
+			"requestId": rentRequest.ID,
+			"amount":    -modification.PriceDifference,
+		}
+		err = service.RefundPayment(refundPayload)
+		if err != nil {
+			zap.L().Error("error refunding modification", zap.Uint("modificationId", modification.ID), zap.Error(err))
+		} else {
+			modification.PaymentStatus = "refunded"
+			err = service.modificationRepo.UpdateModification(modification)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = service.settleModification(rentRequest)
+	if err != nil {
+		return nil, err
+	}
+	return newModificationResponse(modification), nil
+}
+
+func (service *RentService) RejectModification(ownerId uint, rentRequestIdStr, modificationIdStr string) error {
+	rentRequest, modification, err := service.getModification(rentRequestIdStr, modificationIdStr)
+	if err != nil {
+		return err
+	}
+
+	if rentRequest.OwnerID != ownerId {
+		return ErrNotAllowed
+	}
+
+	if modification.Status != "pending" {
+		return ErrNotAllowed
+	}
+
+	modification.Status = "rejected"
+	modification.UpdatedAt = time.Now()
+	return service.modificationRepo.UpdateModification(modification)
+}
+
+func (service *RentService) PayModification(renterId uint, rentRequestIdStr, modificationIdStr string) (*string, error) {
+	rentRequest, modification, err := service.getModification(rentRequestIdStr, modificationIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if rentRequest.RenterID != renterId {
+		return nil, ErrNotAllowed
+	}
+
+	if modification.Status != "awaiting payment" {
+		return nil, ErrNotAllowed
+	}
+
+	paymentPayload := map[string]interface{}{
+		"requestId":   rentRequest.ID,
+		"amount":      modification.PriceDifference,
+		"callbackURL": fmt.Sprintf("%s/rent-request/modification-callback?modificationId=%v", service.config.CallbackBaseURL, modification.ID),
+	}
+
+	return service.CreatePaymentRequest(paymentPayload)
+}
+
+func (service *RentService) UpdateModificationPaymentStatus(modificationIdStr, status string) (*string, error) {
+	modificationId, err := strconv.ParseUint(modificationIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	modification, err := service.modificationRepo.GetModificationById(uint(modificationId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	// A modification canceled along with its booking may still be paid by a
+	// renter who started paying before, and has to be refunded.
+	payable := modification.Status == "awaiting payment" || (modification.Status == "canceled" && modification.PaymentStatus == "pending")
+	if !payable {
+		return nil, ErrNotAllowed
+	}
+
+	if status != "success" && status != "cancel" {
+		return nil, fmt.Errorf("invalid payment status %q", status)
+	}
+
+	rentRequest, err := service.repo.GetRentRequestsById(modification.RentRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	modification.PaymentStatus = status
+	modification.UpdatedAt = time.Now()
+
+	if status == "cancel" {
+		err = service.modificationRepo.UpdateModification(modification)
+		if err != nil {
+			return nil, err
+		}
+		message := "Your payment has been canceled"
+		return &message, nil
+	}
+
+	if modification.Status == "canceled" || rentRequest.Status != "paid" {
+		err = service.refundModification(rentRequest, modification)
+		if err != nil {
+			return nil, err
+		}
+		message := "The booking is no longer active, your payment has been refunded"
+		return &message, nil
+	}
+
+	// The new dates may have been booked by someone else while the renter
+	// was paying, in which case the difference goes back to the renter.
+	postDetail, err := service.serviceClient.GetPostByID(rentRequest.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.checkAvailability(rentRequest.PostID, inventory(postDetail), bookedQuantity(rentRequest), modification.StartDate, modification.EndDate, rentRequest.ID)
+	if errors.Is(err, ErrConflict) {
+		err = service.refundModification(rentRequest, modification)
+		if err != nil {
+			return nil, err
+		}
+		message := "The new dates are no longer available, your payment has been refunded"
+		return &message, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.applyModification(rentRequest, modification)
+	if err != nil {
+		return nil, err
+	}
+	err = service.settleModification(rentRequest)
+	if err != nil {
+		return nil, err
+	}
+	message := "Your payment was processed successfully!"
+	return &message, nil
+}
+
+// priceModification checks the requested dates against paid bookings and
+// fills in the new total price and the difference with the current one.
+func (service *RentService) priceModification(rentRequest *RentRequest, modification *RentModification) error {
+	postDetail, err := service.serviceClient.GetPostByID(rentRequest.PostID)
+	if err != nil {
+		return err
+	}
+
+	err = service.checkAvailability(rentRequest.PostID, inventory(postDetail), bookedQuantity(rentRequest), modification.StartDate, modification.EndDate, rentRequest.ID)
+	if err != nil {
+		return err
+	}
+
+	totalPrice, err := calculateTotalPrice(postDetail, bookedQuantity(rentRequest), modification.StartDate, modification.EndDate)
+	if err != nil {
+		return err
+	}
+
+	modification.TotalPrice = totalPrice
+	modification.PriceDifference = totalPrice - rentRequest.TotalPrice
+	return nil
+}
+
+// applyModification saves the new dates of the rent request and the applied
+// modification in one transaction.
+func (service *RentService) applyModification(rentRequest *RentRequest, modification *RentModification) error {
+	modification.Status = "applied"
+	modification.UpdatedAt = time.Now()
+	rentRequest.StartDate = modification.StartDate
+	rentRequest.EndDate = modification.EndDate
+	rentRequest.TotalPrice = modification.TotalPrice
+	rentRequest.UpdatedAt = time.Now()
+	err := service.modificationRepo.ApplyModificationWithEvent(modification, rentRequest, events.RentRequestModified)
+	if err != nil {
+		return err
+	}
+	service.broadcastChange(events.RentRequestModified, rentRequest)
+	return nil
+}
+
+// settleModification updates the other requests once the new dates of a
+// rent request are saved.
+func (service *RentService) settleModification(rentRequest *RentRequest) error {
+	if rentRequest.Status == "paid" {
+		return service.rejectOverlappingRequests(rentRequest)
+	}
+	return service.refreshBookingGroup(rentRequest.BookingGroupID)
+}
+
+// refundModification gives the renter back the difference they paid for a
+// modification that cannot be applied, and rejects it.
+func (service *RentService) refundModification(rentRequest *RentRequest, modification *RentModification) error {
+	refundPayload := map[string]interface{}{
+		"requestId": rentRequest.ID,
+		"amount":    modification.PriceDifference,
+	}
+	err := service.RefundPayment(refundPayload)
+	if err != nil {
+		return err
+	}
+	modification.Status = "rejected"
+	modification.PaymentStatus = "refunded"
+	modification.UpdatedAt = time.Now()
+	return service.modificationRepo.UpdateModification(modification)
+}
+
+// cancelOpenModifications calls off the modifications of a rent request that
+// is no longer active. The change ending it is already saved by then, so
+// failures are logged rather than returned.
+func (service *RentService) cancelOpenModifications(rentRequestId uint) {
+	err := service.modificationRepo.CancelOpenModifications(rentRequestId)
+	if err != nil {
+		zap.L().Error("error canceling open modifications", zap.Uint("rentRequestId", rentRequestId), zap.Error(err))
+	}
+}
+
+func (service *RentService) getModification(rentRequestIdStr, modificationIdStr string) (*RentRequest, *RentModification, error) {
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	modificationId, err := strconv.ParseUint(modificationIdStr, 10, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	modification, err := service.modificationRepo.GetModificationById(uint(modificationId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrRecordNotFound
+		}
+		return nil, nil, err
+	}
+
+	if modification.RentRequestID != rentRequest.ID {
+		return nil, nil, ErrRecordNotFound
+	}
+
+	return rentRequest, modification, nil
+}
+
+func newModificationResponse(modification *RentModification) *ModificationResponse {
+	return &ModificationResponse{
+		ID:              modification.ID,
+		StartDate:       modification.StartDate,
+		EndDate:         modification.EndDate,
+		TotalPrice:      modification.TotalPrice,
+		PriceDifference: modification.PriceDifference,
+		Status:          modification.Status,
+		PaymentStatus:   modification.PaymentStatus,
+		CreatedAt:       modification.CreatedAt,
+	}
+}
@@ -0,0 +1,152 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// OfferDto dates follow the same local format as RentDto.
+type OfferDto struct {
+	StartDate  *string `json:"startDate"`
+	EndDate    *string `json:"endDate"`
+	TotalPrice *int    `json:"totalPrice" validate:"omitempty,min=1"`
+}
+
+func (handler *RentHandler) ProposeOffer(c echo.Context) error {
+	var offer OfferDto
+
+	ownerId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	if err := c.Bind(&offer); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(offer); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	createdOffer, err := handler.service.ProposeOffer(ownerId, rentRequestIdStr, offer)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to propose offer", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		} else if errors.Is(err, ErrInvalidOffer) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidOffer.Error())
+		} else if errors.Is(err, ErrConflict) {
+			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		}
+		zap.L().Error("error proposing offer", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to propose offer")
+	}
+
+	return c.JSON(http.StatusCreated, createdOffer)
+}
+
+func (handler *RentHandler) GetOffers(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	if rentRequestIdStr == "" {
+		zap.L().Error("missed rentRequestId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID is required")
+	}
+
+	offers, err := handler.service.GetOffers(userId, rentRequestIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "rent request not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to retrieve offers", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error getting offers", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch offers")
+	}
+
+	return c.JSON(http.StatusOK, offers)
+}
+
+func (handler *RentHandler) AcceptOffer(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	offerIdStr := c.Param("offerId")
+	if rentRequestIdStr == "" || offerIdStr == "" {
+		zap.L().Error("missed rentRequestId or offerId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID and offer ID are required")
+	}
+
+	err := handler.service.AcceptOffer(renterId, rentRequestIdStr, offerIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "offer not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to accept offer", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		} else if errors.Is(err, ErrConflict) {
+			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		}
+		zap.L().Error("error accepting offer", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to accept offer")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the offer has been accepted and the rent request confirmed"})
+}
+
+func (handler *RentHandler) DeclineOffer(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	rentRequestIdStr := c.Param("rentRequestId")
+	offerIdStr := c.Param("offerId")
+	if rentRequestIdStr == "" || offerIdStr == "" {
+		zap.L().Error("missed rentRequestId or offerId")
+		return echo.NewHTTPError(http.StatusBadRequest, "rent-request ID and offer ID are required")
+	}
+
+	err := handler.service.DeclineOffer(renterId, rentRequestIdStr, offerIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "offer not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to decline offer", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error declining offer", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to decline offer")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the offer has been declined"})
+}
@@ -0,0 +1,54 @@
+package rent
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RentOffer struct {
+	ID            uint
+	RentRequestID uint
+	StartDate     time.Time
+	EndDate       time.Time
+	TotalPrice    int
+	PriceProposed bool
+	Status        string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type OfferRepository struct {
+	db *gorm.DB
+}
+
+func NewOfferRepository(db *gorm.DB) *OfferRepository {
+	return &OfferRepository{db: db}
+}
+
+func (offerRepo *OfferRepository) AddOffer(offer *RentOffer) error {
+	return offerRepo.db.Create(&offer).Error
+}
+
+func (offerRepo *OfferRepository) UpdateOffer(offer *RentOffer) error {
+	return offerRepo.db.Save(&offer).Error
+}
+
+func (offerRepo *OfferRepository) GetOfferById(offerId uint) (*RentOffer, error) {
+	var offer RentOffer
+	err := offerRepo.db.First(&offer, offerId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (offerRepo *OfferRepository) GetOffers(rentRequestId uint) ([]RentOffer, error) {
+	var offerList []RentOffer
+	err := offerRepo.db.Model(&RentOffer{}).Where("rent_request_id = ?", rentRequestId).Order("created_at desc, id desc").Find(&offerList).Error
+	return offerList, err
+}
+
+func (offerRepo *OfferRepository) SupersedePendingOffers(rentRequestId uint, updatedAt time.Time) error {
+	return offerRepo.db.Model(&RentOffer{}).Where("rent_request_id = ? and status = ?", rentRequestId, "pending").Updates(map[string]interface{}{"status": "superseded", "updated_at": updatedAt}).Error
+}
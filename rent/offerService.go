@@ -0,0 +1,235 @@
+package rent
+
+import (
+	"errors"
+	"fmt"
+	"rental_service/events"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidOffer = errors.New("offer must change the dates or the price")
+
+type OfferResponse struct {
+	ID         uint      `json:"id"`
+	StartDate  time.Time `json:"start_date"`
+	EndDate    time.Time `json:"end_date"`
+	TotalPrice int       `json:"total_price"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ProposeOffer lets the owner answer a waiting request with alternate dates
+// and/or price instead of confirming it as is. A new offer supersedes the
+// previous pending one.
+func (service *RentService) ProposeOffer(ownerId uint, rentRequestIdStr string, offerDto OfferDto) (*OfferResponse, error) {
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if rentRequest.OwnerID != ownerId {
+		return nil, ErrNotAllowed
+	}
+
+	if rentRequest.Status != "waiting for confirmation" {
+		return nil, ErrNotAllowed
+	}
+
+	postDetail, err := service.serviceClient.GetPostByID(rentRequest.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate := rentRequest.StartDate
+	if offerDto.StartDate != nil {
+		startDate, err = resolveDate(postDetail, *offerDto.StartDate, postDetail.CheckInTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	endDate := rentRequest.EndDate
+	if offerDto.EndDate != nil {
+		endDate, err = resolveEndDate(postDetail, *offerDto.EndDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !startDate.Before(endDate) {
+		return nil, fmt.Errorf("invalid date")
+	}
+
+	datesChanged := !startDate.Equal(rentRequest.StartDate) || !endDate.Equal(rentRequest.EndDate)
+	if !datesChanged && offerDto.TotalPrice == nil {
+		return nil, ErrInvalidOffer
+	}
+
+	err = service.checkAvailability(rentRequest.PostID, inventory(postDetail), bookedQuantity(rentRequest), startDate, endDate, rentRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPrice, err := calculateTotalPrice(postDetail, bookedQuantity(rentRequest), startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	if offerDto.TotalPrice != nil {
+		totalPrice = *offerDto.TotalPrice
+	}
+
+	now := time.Now()
+	err = service.offerRepo.SupersedePendingOffers(rentRequest.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	offer := &RentOffer{
+		RentRequestID: rentRequest.ID,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		TotalPrice:    totalPrice,
+		PriceProposed: offerDto.TotalPrice != nil,
+		Status:        "pending",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	err = service.offerRepo.AddOffer(offer)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOfferResponse(offer), nil
+}
+
+func (service *RentService) GetOffers(userId uint, rentRequestIdStr string) ([]OfferResponse, error) {
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId != rentRequest.RenterID && userId != rentRequest.OwnerID {
+		return nil, ErrNotAllowed
+	}
+
+	offers, err := service.offerRepo.GetOffers(rentRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	offerResponseList := []OfferResponse{}
+	for _, offer := range offers {
+		offerResponseList = append(offerResponseList, *newOfferResponse(&offer))
+	}
+	return offerResponseList, nil
+}
+
+// AcceptOffer applies a pending offer to the rent request and confirms it.
+// Availability and pricing are checked again because the post may have been
+// booked or repriced since the offer was made.
+func (service *RentService) AcceptOffer(renterId uint, rentRequestIdStr, offerIdStr string) error {
+	rentRequest, offer, err := service.getPendingOffer(renterId, rentRequestIdStr, offerIdStr)
+	if err != nil {
+		return err
+	}
+
+	postDetail, err := service.serviceClient.GetPostByID(rentRequest.PostID)
+	if err != nil {
+		return err
+	}
+
+	err = service.checkAvailability(rentRequest.PostID, inventory(postDetail), bookedQuantity(rentRequest), offer.StartDate, offer.EndDate, rentRequest.ID)
+	if err != nil {
+		return err
+	}
+
+	totalPrice, err := calculateTotalPrice(postDetail, bookedQuantity(rentRequest), offer.StartDate, offer.EndDate)
+	if err != nil {
+		return err
+	}
+	if offer.PriceProposed {
+		totalPrice = offer.TotalPrice
+	}
+
+	now := time.Now()
+	offer.TotalPrice = totalPrice
+	offer.Status = "accepted"
+	offer.UpdatedAt = now
+	err = service.offerRepo.UpdateOffer(offer)
+	if err != nil {
+		return err
+	}
+
+	rentRequest.StartDate = offer.StartDate
+	rentRequest.EndDate = offer.EndDate
+	rentRequest.TotalPrice = totalPrice
+	rentRequest.Status = "Confirmed"
+	rentRequest.UpdatedAt = now
+	err = service.updateRentRequest(rentRequest, events.RentRequestConfirmed)
+	if err != nil {
+		return err
+	}
+	return service.refreshBookingGroup(rentRequest.BookingGroupID)
+}
+
+func (service *RentService) DeclineOffer(renterId uint, rentRequestIdStr, offerIdStr string) error {
+	_, offer, err := service.getPendingOffer(renterId, rentRequestIdStr, offerIdStr)
+	if err != nil {
+		return err
+	}
+
+	offer.Status = "declined"
+	offer.UpdatedAt = time.Now()
+	return service.offerRepo.UpdateOffer(offer)
+}
+
+func (service *RentService) getPendingOffer(renterId uint, rentRequestIdStr, offerIdStr string) (*RentRequest, *RentOffer, error) {
+	rentRequest, err := service.getRentRequest(rentRequestIdStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rentRequest.RenterID != renterId {
+		return nil, nil, ErrNotAllowed
+	}
+
+	if rentRequest.Status != "waiting for confirmation" {
+		return nil, nil, ErrNotAllowed
+	}
+
+	offerId, err := strconv.ParseUint(offerIdStr, 10, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	offer, err := service.offerRepo.GetOfferById(uint(offerId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrRecordNotFound
+		}
+		return nil, nil, err
+	}
+
+	if offer.RentRequestID != rentRequest.ID {
+		return nil, nil, ErrRecordNotFound
+	}
+
+	if offer.Status != "pending" {
+		return nil, nil, ErrNotAllowed
+	}
+
+	return rentRequest, offer, nil
+}
+
+func newOfferResponse(offer *RentOffer) *OfferResponse {
+	return &OfferResponse{
+		ID:         offer.ID,
+		StartDate:  offer.StartDate,
+		EndDate:    offer.EndDate,
+		TotalPrice: offer.TotalPrice,
+		Status:     offer.Status,
+		CreatedAt:  offer.CreatedAt,
+	}
+}
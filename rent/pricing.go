@@ -0,0 +1,192 @@
+package rent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	RentalUnitHour  = "hour"
+	RentalUnitDay   = "day"
+	RentalUnitNight = "night"
+	RentalUnitWeek  = "week"
+)
+
+const (
+	localDateLayout         = "2006-01-02"
+	localDateTimeLayout     = "2006-01-02T15:04"
+	localDateTimeSecsLayout = "2006-01-02T15:04:05"
+	clockLayout             = "15:04"
+)
+
+var ErrInvalidPeriod = errors.New("the period does not match the rental unit of the post")
+
+// rentalUnit returns the unit the post is rented by. Posts that predate
+// rental units are rented by the day.
+func rentalUnit(postDetail *PostResponseWithOwner) string {
+	if postDetail.RentalUnit == "" {
+		return RentalUnitDay
+	}
+	return postDetail.RentalUnit
+}
+
+// pricePerUnit falls back to the daily price for posts that only publish one.
+func pricePerUnit(postDetail *PostResponseWithOwner) float64 {
+	if postDetail.PricePerUnit > 0 {
+		return postDetail.PricePerUnit
+	}
+	return postDetail.PricePerDay
+}
+
+// postLocation returns the time zone the post is rented in. Posts without a
+// time zone are treated as UTC.
+func postLocation(postDetail *PostResponseWithOwner) (*time.Location, error) {
+	if postDetail.TimeZone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(postDetail.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone of post: %w", err)
+	}
+	return location, nil
+}
+
+// parseClock parses a check-in or check-out time like "15:00". Posts without
+// one hand over at midnight.
+func parseClock(value string) (int, int, error) {
+	if value == "" {
+		return 0, 0, nil
+	}
+	clock, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid check-in/check-out time of post: %w", err)
+	}
+	return clock.Hour(), clock.Minute(), nil
+}
+
+// resolvePeriod turns the dates sent by a renter into instants in the post's
+// time zone. Hourly posts take local date-times ("2024-03-30T14:00"); the
+// other units take local calendar dates ("2024-03-30") which are placed at the
+// post's check-in and check-out times. RFC 3339 timestamps are accepted too
+// and are read in the post's time zone.
+func resolvePeriod(postDetail *PostResponseWithOwner, startStr, endStr string) (time.Time, time.Time, error) {
+	startDate, err := resolveDate(postDetail, startStr, postDetail.CheckInTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	endDate, err := resolveDate(postDetail, endStr, postDetail.CheckOutTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if !startDate.Before(endDate) {
+		return time.Time{}, time.Time{}, ErrInvalidPeriod
+	}
+	return startDate, endDate, nil
+}
+
+// resolveEndDate is resolvePeriod for requests that only move the end of an
+// existing period, like extensions.
+func resolveEndDate(postDetail *PostResponseWithOwner, endStr string) (time.Time, error) {
+	return resolveDate(postDetail, endStr, postDetail.CheckOutTime)
+}
+
+func resolveDate(postDetail *PostResponseWithOwner, value, handoverTime string) (time.Time, error) {
+	location, err := postLocation(postDetail)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if rentalUnit(postDetail) == RentalUnitHour {
+		for _, layout := range []string{localDateTimeLayout, localDateTimeSecsLayout} {
+			if date, err := time.ParseInLocation(layout, value, location); err == nil {
+				return date, nil
+			}
+		}
+		if date, err := time.Parse(time.RFC3339, value); err == nil {
+			return date.In(location), nil
+		}
+		return time.Time{}, ErrInvalidPeriod
+	}
+
+	date, err := time.ParseInLocation(localDateLayout, value, location)
+	if err != nil {
+		timestamp, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, ErrInvalidPeriod
+		}
+		date = timestamp.In(location)
+	}
+
+	hour, minute, err := parseClock(handoverTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, location), nil
+}
+
+// localNights counts the calendar days between two instants in the post's
+// time zone, so DST transitions do not add or remove a night.
+func localNights(location *time.Location, startDate, endDate time.Time) int {
+	localStart := startDate.In(location)
+	localEnd := endDate.In(location)
+	startDay := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, time.UTC)
+	endDay := time.Date(localEnd.Year(), localEnd.Month(), localEnd.Day(), 0, 0, 0, 0, time.UTC)
+	return int(endDay.Sub(startDay).Hours() / 24)
+}
+
+// countUnits checks that the period is made of whole units of the post and
+// returns how many. Hourly posts may define their own slots, e.g. 30 or 120
+// minutes, that bookings have to start on and be a multiple of; their units
+// are counted in hours.
+func countUnits(postDetail *PostResponseWithOwner, startDate, endDate time.Time) (float64, error) {
+	if !startDate.Before(endDate) {
+		return 0, ErrInvalidPeriod
+	}
+
+	location, err := postLocation(postDetail)
+	if err != nil {
+		return 0, err
+	}
+
+	switch rentalUnit(postDetail) {
+	case RentalUnitHour:
+		slot := time.Hour
+		if postDetail.SlotMinutes > 0 {
+			slot = time.Duration(postDetail.SlotMinutes) * time.Minute
+		}
+
+		duration := endDate.Sub(startDate)
+		localStart := startDate.In(location)
+		sinceMidnight := time.Duration(localStart.Hour())*time.Hour + time.Duration(localStart.Minute())*time.Minute + time.Duration(localStart.Second())*time.Second
+		if duration%slot != 0 || sinceMidnight%slot != 0 {
+			return 0, ErrInvalidPeriod
+		}
+		return duration.Minutes() / 60, nil
+	case RentalUnitDay, RentalUnitNight:
+		nights := localNights(location, startDate, endDate)
+		if nights <= 0 {
+			return 0, ErrInvalidPeriod
+		}
+		return float64(nights), nil
+	case RentalUnitWeek:
+		nights := localNights(location, startDate, endDate)
+		if nights <= 0 || nights%7 != 0 {
+			return 0, ErrInvalidPeriod
+		}
+		return float64(nights / 7), nil
+	}
+	return 0, errors.New("unknown rental unit " + postDetail.RentalUnit)
+}
+
+func calculateTotalPrice(postDetail *PostResponseWithOwner, quantity int, startDate, endDate time.Time) (int, error) {
+	units, err := countUnits(postDetail, startDate, endDate)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(math.Round(units*pricePerUnit(postDetail))) * quantity, nil
+}
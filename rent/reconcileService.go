@@ -0,0 +1,93 @@
+package rent
+
+import (
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReconcileMatched      = "matched"
+	ReconcileMarkPaid     = "mark paid"
+	ReconcileMarkCanceled = "mark canceled"
+	ReconcileReview       = "review"
+	ReconcileNotFound     = "not found"
+)
+
+// GatewayPayment is the outcome of the payment of a rent request as the
+// payment gateway reports it, with the statuses of its callback.
+type GatewayPayment struct {
+	RentRequestID uint   `json:"requestId"`
+	Status        string `json:"status" validate:"oneof=success cancel"`
+}
+
+type ReconciliationResult struct {
+	RentRequestID uint   `json:"rent_request_id"`
+	GatewayStatus string `json:"gateway_status"`
+	Status        string `json:"status"`
+	PaymentStatus string `json:"payment_status"`
+	Action        string `json:"action"`
+	Applied       bool   `json:"applied"`
+}
+
+// ReconcilePayments compares the payments reported by the gateway with the
+// rent requests. Callbacks that never arrived are replayed when apply is
+// set; other differences, like a request canceled here but paid at the
+// gateway, are left for review. Requests of a bundle are paid through the
+// bundle and always need review.
+func (service *RentService) ReconcilePayments(payments []GatewayPayment, apply bool) ([]ReconciliationResult, error) {
+	results := make([]ReconciliationResult, 0, len(payments))
+	for _, payment := range payments {
+		result := ReconciliationResult{RentRequestID: payment.RentRequestID, GatewayStatus: payment.Status}
+
+		rentRequest, err := service.repo.GetRentRequestsById(payment.RentRequestID)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			result.Action = ReconcileNotFound
+			results = append(results, result)
+			continue
+		}
+		result.Status = rentRequest.Status
+		result.PaymentStatus = rentRequest.PaymentStatus
+		result.Action = reconcileAction(rentRequest, payment.Status)
+
+		if apply && (result.Action == ReconcileMarkPaid || result.Action == ReconcileMarkCanceled) {
+			_, err = service.UpdateRentRequestPaymentStatus(strconv.FormatUint(uint64(rentRequest.ID), 10), payment.Status)
+			if err != nil {
+				return nil, err
+			}
+			result.Applied = true
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func reconcileAction(rentRequest *RentRequest, gatewayStatus string) string {
+	if rentRequest.PaymentStatus == gatewayStatus {
+		return ReconcileMatched
+	}
+	if rentRequest.BookingGroupID != nil {
+		return ReconcileReview
+	}
+
+	switch gatewayStatus {
+	case "success":
+		if rentRequest.Status == "Confirmed" {
+			return ReconcileMarkPaid
+		}
+	case "cancel":
+		if rentRequest.PaymentStatus == "success" || rentRequest.Status == "paid" {
+			return ReconcileReview
+		}
+		if rentRequest.Status == "Confirmed" {
+			return ReconcileMarkCanceled
+		}
+		// Nothing was paid and the request is not payable anymore.
+		return ReconcileMatched
+	}
+	return ReconcileReview
+}
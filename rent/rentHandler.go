@@ -3,7 +3,6 @@ package rent
 import (
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -20,10 +19,14 @@ func NewRentHandler(service *RentService, validate *validator.Validate) *RentHan
 	return &RentHandler{service: service, validate: validate}
 }
 
+// RentDto dates are local to the post: calendar dates ("2024-03-30") for
+// daily, nightly and weekly posts and date-times ("2024-03-30T14:00") for
+// hourly ones.
 type RentDto struct {
-	PostId    uint      `json:"postId" validate:"required"`
-	StartDate time.Time `json:"startDate" validate:"required"`
-	EndDate   time.Time `json:"endDate" validate:"required"`
+	PostId    uint   `json:"postId" validate:"required"`
+	StartDate string `json:"startDate" validate:"required"`
+	EndDate   string `json:"endDate" validate:"required"`
+	Quantity  int    `json:"quantity" validate:"omitempty,min=1"`
 }
 
 func (handler *RentHandler) CreateRentRequest(c echo.Context) error {
@@ -49,6 +52,10 @@ func (handler *RentHandler) CreateRentRequest(c echo.Context) error {
 	if err != nil {
 		if errors.Is(err, ErrConflict) {
 			return c.JSON(http.StatusConflict, "there is already a paid request in this period")
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		} else if errors.Is(err, ErrWaitlistPriority) {
+			return c.JSON(http.StatusConflict, ErrWaitlistPriority.Error())
 		}
 		zap.L().Error("error creating rent request", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create rent request")
@@ -7,17 +7,20 @@ import (
 )
 
 type RentRequest struct {
-	ID            uint
-	RenterID      uint
-	OwnerID       uint
-	PostID        uint
-	StartDate     time.Time
-	EndDate       time.Time
-	TotalPrice    int
-	Status        string
-	PaymentStatus string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID              uint
+	RenterID        uint
+	OwnerID         uint
+	PostID          uint
+	ParentRequestID *uint
+	BookingGroupID  *uint
+	StartDate       time.Time
+	EndDate         time.Time
+	Quantity        int
+	TotalPrice      int
+	Status          string
+	PaymentStatus   string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 type RentRepository struct {
@@ -36,9 +39,33 @@ func (rentRepo *RentRepository) UpdateRentRequest(rentRequest *RentRequest) erro
 	return rentRepo.db.Save(&rentRequest).Error
 }
 
+// AddRentRequestWithEvent stores a new rent request along with the given
+// domain event.
+func (rentRepo *RentRepository) AddRentRequestWithEvent(rentRequest *RentRequest, eventType string) error {
+	return rentRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&rentRequest).Error
+		if err != nil {
+			return err
+		}
+		return enqueueRentRequestEvent(tx, eventType, rentRequest)
+	})
+}
+
+// UpdateRentRequestWithEvent saves a rent request along with the domain event
+// describing the change.
+func (rentRepo *RentRepository) UpdateRentRequestWithEvent(rentRequest *RentRequest, eventType string) error {
+	return rentRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(&rentRequest).Error
+		if err != nil {
+			return err
+		}
+		return enqueueRentRequestEvent(tx, eventType, rentRequest)
+	})
+}
+
 func (rentRepo *RentRepository) GetOvelappingRequest(postId uint, status string, startDate, endDate time.Time) ([]RentRequest, error) {
 	var rentRequestList []RentRequest
-	err := rentRepo.db.Model(&RentRequest{}).Where("post_id = ? and status = ? and start_date <= ? and end_date >= ?", postId, status, endDate, startDate).Find(&rentRequestList).Error
+	err := rentRepo.db.Model(&RentRequest{}).Where("post_id = ? and status = ? and start_date < ? and end_date > ?", postId, status, endDate, startDate).Find(&rentRequestList).Error
 	return rentRequestList, err
 }
 
@@ -88,3 +115,27 @@ func (rentRepo *RentRepository) GetRenterRentRequests(renterId uint, status stri
 	err := query.Offset(offset).Limit(limit).Find(&rentRequestList).Error
 	return rentRequestList, err
 }
+
+func (rentRepo *RentRepository) CountRenterRentRequests(renterId uint, status string) (int64, error) {
+	var count int64
+	err := rentRepo.db.Model(&RentRequest{}).Where("renter_id = ? and status = ?", renterId, status).Count(&count).Error
+	return count, err
+}
+
+func (rentRepo *RentRepository) GetGroupRentRequests(groupId uint) ([]RentRequest, error) {
+	var rentRequestList []RentRequest
+	err := rentRepo.db.Model(&RentRequest{}).Where("booking_group_id = ?", groupId).Order("id").Find(&rentRequestList).Error
+	return rentRequestList, err
+}
+
+func (rentRepo *RentRepository) GetFinishedRentRequests(status string, before time.Time) ([]RentRequest, error) {
+	var rentRequestList []RentRequest
+	err := rentRepo.db.Model(&RentRequest{}).Where("status = ? and end_date <= ?", status, before).Find(&rentRequestList).Error
+	return rentRequestList, err
+}
+
+func (rentRepo *RentRepository) GetUpcomingPostRentRequests(postId uint, statuses []string, after time.Time) ([]RentRequest, error) {
+	var rentRequestList []RentRequest
+	err := rentRepo.db.Model(&RentRequest{}).Where("post_id = ? and status IN ? and end_date > ?", postId, statuses, after).Order("start_date").Find(&rentRequestList).Error
+	return rentRequestList, err
+}
@@ -5,120 +5,213 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"rental_service/events"
+	"rental_service/metrics"
 	"strconv"
 	"strings"
 	"time"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type RentService struct {
-	repo *RentRepository
+	repo             *RentRepository
+	messageRepo      *MessageRepository
+	offerRepo        *OfferRepository
+	modificationRepo *ModificationRepository
+	instantBookRepo  *InstantBookRepository
+	waitlistRepo     *WaitlistRepository
+	bundleRepo       *BundleRepository
+
+	waitlistNotifier WaitlistNotifier
+	broadcaster      Broadcaster
+	serviceClient    *ServiceClient
+	config           Config
+	client           *http.Client
 }
 
-func NewRentService(repo *RentRepository) *RentService {
-	return &RentService{repo: repo}
+func NewRentService(repo *RentRepository, messageRepo *MessageRepository, offerRepo *OfferRepository, modificationRepo *ModificationRepository, instantBookRepo *InstantBookRepository, waitlistRepo *WaitlistRepository, bundleRepo *BundleRepository, waitlistNotifier WaitlistNotifier, broadcaster Broadcaster, serviceClient *ServiceClient, config Config) *RentService {
+	return &RentService{
+		repo:             repo,
+		messageRepo:      messageRepo,
+		offerRepo:        offerRepo,
+		modificationRepo: modificationRepo,
+		instantBookRepo:  instantBookRepo,
+		waitlistRepo:     waitlistRepo,
+		bundleRepo:       bundleRepo,
+		waitlistNotifier: waitlistNotifier,
+		broadcaster:      broadcaster,
+		serviceClient:    serviceClient,
+		config:           config,
+		client:           &http.Client{Timeout: config.Timeout},
+	}
 }
 
 var ErrConflict = errors.New("there is alreay a paid request for this period")
 var ErrRecordNotFound = errors.New("rentRequest not found")
 var ErrNotAllowed = errors.New("owner ID mismatch")
 
-func (service *RentService) CreateRentRequest(renterID uint, rentRequest RentDto) (*uint, error) {
-	if !rentRequest.StartDate.Before(rentRequest.EndDate) {
-		return nil, fmt.Errorf("invalid date")
-	}
+type CreateRentResponse struct {
+	ID          uint   `json:"id"`
+	Status      string `json:"status"`
+	RedirectURL string `json:"redirectURL,omitempty"`
+}
 
-	rentRequestList, err := service.repo.GetOvelappingRequest(rentRequest.PostId, "Paid", rentRequest.StartDate, rentRequest.EndDate)
+// CreateRentRequest stores a new request waiting for the owner. When the post
+// allows instant booking for this renter the request is confirmed right away
+// and the payment redirect is returned along with it.
+func (service *RentService) CreateRentRequest(renterID uint, rentRequest RentDto) (*CreateRentResponse, error) {
+	newRentRequest, waitlistEntry, err := service.prepareRentRequest(renterID, rentRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(rentRequestList) > 0 {
-		return nil, ErrConflict
+	err = service.addRentRequest(newRentRequest, events.RentRequestCreated)
+	if err != nil {
+
+		return nil, err
 	}
 
-	postDetail, err := GetPostByID(rentRequest.PostId)
+	err = service.markWaitlistBooked(waitlistEntry)
 	if err != nil {
 		return nil, err
 	}
 
-	numberOfDays := int(rentRequest.EndDate.Sub(rentRequest.StartDate).Hours() / 24)
-	if numberOfDays <= 0 {
-		return nil, fmt.Errorf("bad request")
+	createRentResponse := &CreateRentResponse{ID: newRentRequest.ID, Status: newRentRequest.Status}
+	if newRentRequest.Status == "Confirmed" {
+		// The request stays confirmed even if the gateway is down, the renter
+		// can still get a redirect later through the pay endpoint.
+		redirectURL, err := service.requestPayment(newRentRequest)
+		if err != nil {
+			zap.L().Error("error requesting payment for instant booking", zap.Error(err))
+		} else {
+			createRentResponse.RedirectURL = *redirectURL
+		}
+	}
+	return createRentResponse, nil
+}
+
+// prepareRentRequest validates, checks and prices a new request without
+// storing it. It also returns the renter's waitlist entry when the request
+// uses the priority it grants.
+func (service *RentService) prepareRentRequest(renterID uint, rentRequest RentDto) (*RentRequest, *WaitlistEntry, error) {
+	postDetail, err := service.serviceClient.GetPostByID(rentRequest.PostId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	startDate, endDate, err := resolvePeriod(postDetail, rentRequest.StartDate, rentRequest.EndDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	quantity := rentRequest.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity > inventory(postDetail) {
+		return nil, nil, ErrConflict
+	}
+
+	err = service.checkAvailability(rentRequest.PostId, inventory(postDetail), quantity, startDate, endDate, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	waitlistEntry, err := service.checkWaitlistPriority(renterID, rentRequest.PostId, inventory(postDetail), quantity, startDate, endDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	totalPrice, err := calculateTotalPrice(postDetail, quantity, startDate, endDate)
+	if err != nil {
+		return nil, nil, err
 	}
-	totalPrice := numberOfDays * int(postDetail.PricePerDay)
 
 	newRentRequest := &RentRequest{
 		RenterID:      renterID,
 		OwnerID:       postDetail.OwnerId,
 		PostID:        rentRequest.PostId,
-		StartDate:     rentRequest.StartDate,
-		EndDate:       rentRequest.EndDate,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Quantity:      quantity,
 		TotalPrice:    totalPrice,
 		Status:        "waiting for confirmation",
 		PaymentStatus: "pending",
 		CreatedAt:     time.Now(),
 	}
-	err = service.repo.AddRentRequest(newRentRequest)
-	if err != nil {
-
-		return nil, err
-	}
-	return &newRentRequest.ID, nil
-}
-
-type PostResponseWithOwner struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	PricePerDay float64 `json:"pricePerDay"`
-	Address     string  `json:"address"`
-	Category    string  `json:"category"`
-	OwnerId     uint    `json:"ownerId"`
-}
-
-const GetPostByIdUrl = "http://localhost:8081/posts"
 
-func GetPostByID(postId uint) (*PostResponseWithOwner, error) {
-	url := fmt.Sprintf("%s/%d", GetPostByIdUrl, postId)
-	response, err := http.Get(url)
+	instantBook, err := service.canInstantBook(renterID, rentRequest.PostId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch post details : %w", err)
+		return nil, nil, err
 	}
-	defer response.Body.Close()
-
-	if response.StatusCode == http.StatusBadRequest {
-		return nil, fmt.Errorf("invalid post ID or bad request")
-	}
-	if response.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("post not found")
+	if instantBook {
+		newRentRequest.Status = "Confirmed"
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("post not found or an error occurred: status code %d", response.StatusCode)
-	}
+	return newRentRequest, waitlistEntry, nil
+}
 
-	body, err := io.ReadAll(response.Body)
+func (service *RentService) getRentRequest(rentRequestIdStr string) (*RentRequest, error) {
+	rentRequestId, err := strconv.ParseUint(rentRequestIdStr, 10, 32)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	var postResponse PostResponseWithOwner
-	if err := json.Unmarshal(body, &postResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode post response: %w", err)
+	rentRequest, err := service.repo.GetRentRequestsById(uint(rentRequestId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
 	}
+	return rentRequest, nil
+}
 
-	return &postResponse, nil
+type PostResponseWithOwner struct {
+	Title        string  `json:"title"`
+	Description  string  `json:"description"`
+	PricePerDay  float64 `json:"pricePerDay"`
+	Address      string  `json:"address"`
+	Category     string  `json:"category"`
+	OwnerId      uint    `json:"ownerId"`
+	RentalUnit   string  `json:"rentalUnit"`
+	PricePerUnit float64 `json:"pricePerUnit"`
+	SlotMinutes  int     `json:"slotMinutes"`
+	TimeZone     string  `json:"timeZone"`
+	CheckInTime  string  `json:"checkInTime"`
+	CheckOutTime string  `json:"checkOutTime"`
+	Quantity     int     `json:"quantity"`
 }
 
 type RentRequestResponse struct {
-	StartDate     time.Time `json:"start_date" validate:"required"`
-	EndDate       time.Time `json:"end_date" validate:"required"`
-	TotalPrice    int       `json:"total_price"`
-	Status        string    `json:"status"`
-	PaymentStatus string    `json:"payment_status"`
+	ID              uint      `json:"id"`
+	ParentRequestID *uint     `json:"parent_request_id,omitempty"`
+	BookingGroupID  *uint     `json:"booking_group_id,omitempty"`
+	StartDate       time.Time `json:"start_date" validate:"required"`
+	EndDate         time.Time `json:"end_date" validate:"required"`
+	Quantity        int       `json:"quantity"`
+	TotalPrice      int       `json:"total_price"`
+	Status          string    `json:"status"`
+	PaymentStatus   string    `json:"payment_status"`
+	UnreadMessages  int64     `json:"unread_messages"`
+}
+
+func newRentRequestResponse(rentRequest *RentRequest, unreadMessages int64) RentRequestResponse {
+	return RentRequestResponse{
+		ID:              rentRequest.ID,
+		ParentRequestID: rentRequest.ParentRequestID,
+		BookingGroupID:  rentRequest.BookingGroupID,
+		StartDate:       rentRequest.StartDate,
+		EndDate:         rentRequest.EndDate,
+		Quantity:        bookedQuantity(rentRequest),
+		TotalPrice:      rentRequest.TotalPrice,
+		Status:          rentRequest.Status,
+		PaymentStatus:   rentRequest.PaymentStatus,
+		UnreadMessages:  unreadMessages,
+	}
 }
 
 func (service *RentService) GetRentRequestById(userId uint, rentRequestIdStr string) (*RentRequestResponse, error) {
@@ -135,17 +228,17 @@ func (service *RentService) GetRentRequestById(userId uint, rentRequestIdStr str
 		return nil, err
 	}
 
-	if userId != rentRequest.RenterID || userId != rentRequest.OwnerID {
+	if userId != rentRequest.RenterID && userId != rentRequest.OwnerID {
 		return nil, ErrNotAllowed
 	}
 
-	return &RentRequestResponse{
-		StartDate:     rentRequest.StartDate,
-		EndDate:       rentRequest.EndDate,
-		TotalPrice:    rentRequest.TotalPrice,
-		Status:        rentRequest.Status,
-		PaymentStatus: rentRequest.PaymentStatus,
-	}, nil
+	unreadCounts, err := service.messageRepo.CountUnreadMessages(userId, []uint{rentRequest.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	rentRequestResponse := newRentRequestResponse(rentRequest, unreadCounts[rentRequest.ID])
+	return &rentRequestResponse, nil
 }
 
 func (service *RentService) ConfirmRentRequest(rentRequestIdStr string, ownerId uint) error {
@@ -172,11 +265,11 @@ func (service *RentService) ConfirmRentRequest(rentRequestIdStr string, ownerId
 	rentRequest.Status = "Confirmed"
 	rentRequest.UpdatedAt = time.Now()
 
-	err = service.repo.UpdateRentRequest(rentRequest)
+	err = service.updateRentRequest(rentRequest, events.RentRequestConfirmed)
 	if err != nil {
 		return err
 	}
-	return nil
+	return service.refreshBookingGroup(rentRequest.BookingGroupID)
 
 }
 
@@ -202,10 +295,19 @@ func (service *RentService) PayRentRequest(renterId uint, rentRequestIdStr strin
 		return nil, ErrNotAllowed
 	}
 
+	// Requests of a bundle are paid together through the bundle.
+	if rentRequest.BookingGroupID != nil {
+		return nil, ErrNotAllowed
+	}
+
+	return service.requestPayment(rentRequest)
+}
+
+func (service *RentService) requestPayment(rentRequest *RentRequest) (*string, error) {
 	paymentPayload := map[string]interface{}{
 		"requestId":   rentRequest.ID,
 		"amount":      rentRequest.TotalPrice,
-		"callbackURL": fmt.Sprintf("http://localhost:8082/rent-request/callback?requestId=%v", rentRequest.ID),
+		"callbackURL": fmt.Sprintf("%s/rent-request/callback?requestId=%v", service.config.CallbackBaseURL, rentRequest.ID),
 	}
 
 	redirectURL, err := service.CreatePaymentRequest(paymentPayload)
@@ -216,21 +318,22 @@ func (service *RentService) PayRentRequest(renterId uint, rentRequestIdStr strin
 	return redirectURL, nil
 }
 
-func (service *RentService) CreatePaymentRequest(paymentPayload map[string]interface{}) (*string, error) {
+func (service *RentService) CreatePaymentRequest(paymentPayload map[string]interface{}) (redirectURL *string, err error) {
+	defer metrics.ObserveDependency("payment_gateway", "create_payment", time.Now(), &err)
+
 	requestBody, err := json.Marshal(paymentPayload)
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequest("POST", "http://localhost:8083/payment/request", bytes.NewBuffer(requestBody))
+	request, err := http.NewRequest("POST", service.config.PaymentServiceURL+"/payment/request", bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, err
 	}
 
 	request.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	response, err := client.Do(request)
+	response, err := service.client.Do(request)
 	if err != nil {
 		return nil, err
 	}
@@ -250,6 +353,33 @@ func (service *RentService) CreatePaymentRequest(paymentPayload map[string]inter
 	return &result.RedirectURL, nil
 }
 
+func (service *RentService) RefundPayment(refundPayload map[string]interface{}) (err error) {
+	defer metrics.ObserveDependency("payment_gateway", "refund", time.Now(), &err)
+
+	requestBody, err := json.Marshal(refundPayload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest("POST", service.config.PaymentServiceURL+"/payment/refund", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := service.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		return fmt.Errorf("an error occurred: status code %d", response.StatusCode)
+	}
+
+	return nil
+}
+
 func (service *RentService) UpdateRentRequestPaymentStatus(rentRequestIdStr, status string) (*string, error) {
 	rentRequestId, err := strconv.ParseUint(rentRequestIdStr, 10, 32)
 	if err != nil {
@@ -280,31 +410,14 @@ func (service *RentService) UpdateRentRequestPaymentStatus(rentRequestIdStr, sta
 
 	if status == "success" {
 		rentRequest.Status = "paid"
-		err = service.repo.UpdateRentRequest(rentRequest)
+		err = service.updateRentRequest(rentRequest, events.RentRequestPaid)
 		if err != nil {
 			return nil, err
 		}
 
-		states := []string{"waiting for confirmation", "Confirmed"}
-		postId := rentRequest.PostID
-		startDate := rentRequest.StartDate
-		endDate := rentRequest.EndDate
-		for _, state := range states {
-			rentRequestList, err := service.repo.GetOvelappingRequest(postId, state, startDate, endDate)
-			if err != nil {
-				return nil, err
-			}
-
-			for _, overlappingRequest := range rentRequestList {
-				if overlappingRequest.ID != uint(rentRequestId) {
-					overlappingRequest.Status = "Rejected"
-					overlappingRequest.UpdatedAt = time.Now()
-					err = service.repo.UpdateRentRequest(&overlappingRequest)
-					if err != nil {
-						return nil, err
-					}
-				}
-			}
+		err = service.rejectOverlappingRequests(rentRequest)
+		if err != nil {
+			return nil, err
 		}
 
 		message := "Your payment was processed successfully!"
@@ -340,15 +453,39 @@ func (service *RentService) CancelRentRequest(renterId uint, rentRequestIdStr st
 	if rentRequest.Status == "Confirmed" || rentRequest.Status == "waiting for confirmation" {
 		rentRequest.Status = "canceled"
 		rentRequest.UpdatedAt = time.Now()
-		err := service.repo.UpdateRentRequest(rentRequest)
+		err := service.updateRentRequest(rentRequest, events.RentRequestCancelled)
 		if err != nil {
 			return err
 		}
+		service.cancelOpenModifications(rentRequest.ID)
+		return service.refreshBookingGroup(rentRequest.BookingGroupID)
 
-	} else {
-		return err
+	} else if rentRequest.Status == "paid" {
+		refundPayload := map[string]interface{}{
+			"requestId": rentRequest.ID,
+			"amount":    rentRequest.TotalPrice,
+		}
+		if rentRequest.BookingGroupID != nil {
+			refundPayload["bookingGroupId"] = *rentRequest.BookingGroupID
+		}
+		err = service.RefundPayment(refundPayload)
+		if err != nil {
+			return err
+		}
+
+		rentRequest.Status = "canceled"
+		rentRequest.PaymentStatus = "refunded"
+		rentRequest.UpdatedAt = time.Now()
+		err = service.updateRentRequest(rentRequest, events.RentRequestCancelled)
+		if err != nil {
+			return err
+		}
+
+		service.cancelOpenModifications(rentRequest.ID)
+		service.releaseWaitlist(rentRequest.PostID, rentRequest.StartDate, rentRequest.EndDate)
+		return nil
 	}
-	return nil
+	return err
 }
 
 func (service *RentService) GetOwnerRentRequests(ownerId uint, status, dateStr, pageStr string) ([]RentRequestResponse, error) {
@@ -389,15 +526,18 @@ func (service *RentService) GetOwnerRentRequests(ownerId uint, status, dateStr,
 		return nil, err
 	}
 
+	rentRequestIds := make([]uint, 0, len(rents))
+	for _, rent := range rents {
+		rentRequestIds = append(rentRequestIds, rent.ID)
+	}
+	unreadCounts, err := service.messageRepo.CountUnreadMessages(ownerId, rentRequestIds)
+	if err != nil {
+		return nil, err
+	}
+
 	var rentResponseList []RentRequestResponse
 	for _, rent := range rents {
-		rentResponseList = append(rentResponseList, RentRequestResponse{
-			StartDate:     rent.StartDate,
-			EndDate:       rent.EndDate,
-			TotalPrice:    rent.TotalPrice,
-			Status:        rent.Status,
-			PaymentStatus: rent.PaymentStatus,
-		})
+		rentResponseList = append(rentResponseList, newRentRequestResponse(&rent, unreadCounts[rent.ID]))
 	}
 
 	return rentResponseList, nil
@@ -439,15 +579,18 @@ func (service *RentService) GetRenterRentRequests(renterId uint, status, dateStr
 		return nil, err
 	}
 
+	rentRequestIds := make([]uint, 0, len(rents))
+	for _, rent := range rents {
+		rentRequestIds = append(rentRequestIds, rent.ID)
+	}
+	unreadCounts, err := service.messageRepo.CountUnreadMessages(renterId, rentRequestIds)
+	if err != nil {
+		return nil, err
+	}
+
 	var rentResponseList []RentRequestResponse
 	for _, rent := range rents {
-		rentResponseList = append(rentResponseList, RentRequestResponse{
-			StartDate:     rent.StartDate,
-			EndDate:       rent.EndDate,
-			TotalPrice:    rent.TotalPrice,
-			Status:        rent.Status,
-			PaymentStatus: rent.PaymentStatus,
-		})
+		rentResponseList = append(rentResponseList, newRentRequestResponse(&rent, unreadCounts[rent.ID]))
 	}
 
 	return rentResponseList, nil
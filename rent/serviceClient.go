@@ -0,0 +1,91 @@
+package rent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"rental_service/metrics"
+	"time"
+)
+
+// Config locates the services we depend on. CallbackBaseURL is our own public
+// URL, which the payment service calls back.
+type Config struct {
+	PostServiceURL    string        `yaml:"postServiceUrl" toml:"postServiceUrl" env:"POST_SERVICE_URL" validate:"required,url"`
+	UserServiceURL    string        `yaml:"userServiceUrl" toml:"userServiceUrl" env:"USER_SERVICE_URL" validate:"required,url"`
+	PaymentServiceURL string        `yaml:"paymentServiceUrl" toml:"paymentServiceUrl" env:"PAYMENT_SERVICE_URL" validate:"required,url"`
+	CallbackBaseURL   string        `yaml:"callbackBaseUrl" toml:"callbackBaseUrl" env:"CALLBACK_BASE_URL" validate:"required,url"`
+	Timeout           time.Duration `yaml:"timeout" toml:"timeout" env:"SERVICE_TIMEOUT" validate:"min=0"`
+}
+
+// ServiceClient fetches the posts and users of the post and user services.
+type ServiceClient struct {
+	config Config
+	client *http.Client
+}
+
+func NewServiceClient(config Config) *ServiceClient {
+	return &ServiceClient{config: config, client: &http.Client{Timeout: config.Timeout}}
+}
+
+func (serviceClient *ServiceClient) GetPostByID(postId uint) (post *PostResponseWithOwner, err error) {
+	defer metrics.ObserveDependency("post_service", "get_post", time.Now(), &err)
+
+	url := fmt.Sprintf("%s/posts/%d", serviceClient.config.PostServiceURL, postId)
+	response, err := serviceClient.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post details : %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusBadRequest {
+		return nil, fmt.Errorf("invalid post ID or bad request")
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("post not found")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("post not found or an error occurred: status code %d", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var postResponse PostResponseWithOwner
+	if err := json.Unmarshal(body, &postResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode post response: %w", err)
+	}
+
+	return &postResponse, nil
+}
+
+func (serviceClient *ServiceClient) GetUserByID(userId uint) (user *UserResponse, err error) {
+	defer metrics.ObserveDependency("user_service", "get_user", time.Now(), &err)
+
+	url := fmt.Sprintf("%s/users/%d", serviceClient.config.UserServiceURL, userId)
+	response, err := serviceClient.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user details : %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user not found or an error occurred: status code %d", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var userResponse UserResponse
+	if err := json.Unmarshal(body, &userResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode user response: %w", err)
+	}
+
+	return &userResponse, nil
+}
@@ -0,0 +1,94 @@
+package rent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// WaitlistDto dates follow the same local format as RentDto.
+type WaitlistDto struct {
+	PostId    uint   `json:"postId" validate:"required"`
+	StartDate string `json:"startDate" validate:"required"`
+	EndDate   string `json:"endDate" validate:"required"`
+	Quantity  int    `json:"quantity" validate:"omitempty,min=1"`
+}
+
+func (handler *RentHandler) JoinWaitlist(c echo.Context) error {
+	var waitlist WaitlistDto
+
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err := c.Bind(&waitlist); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(waitlist); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	entry, err := handler.service.JoinWaitlist(renterId, waitlist)
+	if err != nil {
+		if errors.Is(err, ErrPeriodAvailable) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrPeriodAvailable.Error())
+		} else if errors.Is(err, ErrInvalidPeriod) {
+			return echo.NewHTTPError(http.StatusBadRequest, ErrInvalidPeriod.Error())
+		}
+		zap.L().Error("error joining waitlist", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to join waitlist")
+	}
+
+	return c.JSON(http.StatusCreated, entry)
+}
+
+func (handler *RentHandler) LeaveWaitlist(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	waitlistIdStr := c.Param("waitlistId")
+	if waitlistIdStr == "" {
+		zap.L().Error("missed waitlistId")
+		return echo.NewHTTPError(http.StatusBadRequest, "waitlist ID is required")
+	}
+
+	err := handler.service.LeaveWaitlist(renterId, waitlistIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "waitlist entry not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to leave waitlist", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error leaving waitlist", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to leave waitlist")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "you have left the waitlist"})
+}
+
+func (handler *RentHandler) GetWaitlist(c echo.Context) error {
+	renterId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	entries, err := handler.service.GetWaitlist(renterId)
+	if err != nil {
+		zap.L().Error("error getting waitlist", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch waitlist")
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
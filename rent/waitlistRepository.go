@@ -0,0 +1,66 @@
+package rent
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WaitlistEntry struct {
+	ID            uint
+	PostID        uint
+	RenterID      uint
+	StartDate     time.Time
+	EndDate       time.Time
+	Quantity      int
+	Status        string
+	NotifiedAt    *time.Time
+	PriorityUntil *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type WaitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) *WaitlistRepository {
+	return &WaitlistRepository{db: db}
+}
+
+func (waitlistRepo *WaitlistRepository) AddEntry(entry *WaitlistEntry) error {
+	return waitlistRepo.db.Create(&entry).Error
+}
+
+func (waitlistRepo *WaitlistRepository) UpdateEntry(entry *WaitlistEntry) error {
+	return waitlistRepo.db.Save(&entry).Error
+}
+
+func (waitlistRepo *WaitlistRepository) GetEntryById(entryId uint) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	err := waitlistRepo.db.First(&entry, entryId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (waitlistRepo *WaitlistRepository) GetRenterEntries(renterId uint) ([]WaitlistEntry, error) {
+	var entryList []WaitlistEntry
+	err := waitlistRepo.db.Model(&WaitlistEntry{}).Where("renter_id = ?", renterId).Order("created_at desc").Find(&entryList).Error
+	return entryList, err
+}
+
+// GetOverlappingEntries returns the entries of a post in the given status whose
+// period overlaps the given one, oldest first.
+func (waitlistRepo *WaitlistRepository) GetOverlappingEntries(postId uint, status string, startDate, endDate time.Time) ([]WaitlistEntry, error) {
+	var entryList []WaitlistEntry
+	err := waitlistRepo.db.Model(&WaitlistEntry{}).Where("post_id = ? and status = ? and start_date < ? and end_date > ?", postId, status, endDate, startDate).Order("created_at asc, id asc").Find(&entryList).Error
+	return entryList, err
+}
+
+func (waitlistRepo *WaitlistRepository) GetExpiredPriorityEntries(now time.Time) ([]WaitlistEntry, error) {
+	var entryList []WaitlistEntry
+	err := waitlistRepo.db.Model(&WaitlistEntry{}).Where("status = ? and priority_until <= ?", "notified", now).Find(&entryList).Error
+	return entryList, err
+}
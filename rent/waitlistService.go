@@ -0,0 +1,314 @@
+package rent
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// waitlistPriorityWindow is how long a notified renter has the period to
+// themselves before the next renter in line is notified.
+const waitlistPriorityWindow = 24 * time.Hour
+
+var ErrPeriodAvailable = errors.New("the period is available and can be booked directly")
+var ErrWaitlistPriority = errors.New("the period is held for a waitlisted renter")
+
+type WaitlistNotifier interface {
+	NotifyWaitlistSpot(entry *WaitlistEntry) error
+}
+
+type LogWaitlistNotifier struct{}
+
+func NewLogWaitlistNotifier() WaitlistNotifier {
+	return &LogWaitlistNotifier{}
+}
+
+func (notifier *LogWaitlistNotifier) NotifyWaitlistSpot(entry *WaitlistEntry) error {
+	zap.L().Info("waitlisted period became available",
+		zap.Uint("waitlistId", entry.ID),
+		zap.Uint("renterId", entry.RenterID),
+		zap.Uint("postId", entry.PostID),
+		zap.Time("priorityUntil", *entry.PriorityUntil),
+	)
+	return nil
+}
+
+type WaitlistResponse struct {
+	ID            uint       `json:"id"`
+	PostID        uint       `json:"post_id"`
+	StartDate     time.Time  `json:"start_date"`
+	EndDate       time.Time  `json:"end_date"`
+	Quantity      int        `json:"quantity"`
+	Status        string     `json:"status"`
+	PriorityUntil *time.Time `json:"priority_until"`
+}
+
+// JoinWaitlist subscribes the renter to a period that is already paid for by
+// someone else.
+func (service *RentService) JoinWaitlist(renterId uint, waitlistDto WaitlistDto) (*WaitlistResponse, error) {
+	postDetail, err := service.serviceClient.GetPostByID(waitlistDto.PostId)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, endDate, err := resolvePeriod(postDetail, waitlistDto.StartDate, waitlistDto.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := waitlistDto.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	err = service.checkAvailability(waitlistDto.PostId, inventory(postDetail), quantity, startDate, endDate, 0)
+	if err == nil {
+		return nil, ErrPeriodAvailable
+	}
+	if !errors.Is(err, ErrConflict) {
+		return nil, err
+	}
+
+	entry := &WaitlistEntry{
+		PostID:    waitlistDto.PostId,
+		RenterID:  renterId,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Quantity:  quantity,
+		Status:    "waiting",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	err = service.waitlistRepo.AddEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWaitlistResponse(entry), nil
+}
+
+func (service *RentService) LeaveWaitlist(renterId uint, entryIdStr string) error {
+	entryId, err := strconv.ParseUint(entryIdStr, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	entry, err := service.waitlistRepo.GetEntryById(uint(entryId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	if entry.RenterID != renterId {
+		return ErrNotAllowed
+	}
+
+	if entry.Status != "waiting" && entry.Status != "notified" {
+		return ErrNotAllowed
+	}
+
+	wasNotified := entry.Status == "notified"
+	entry.Status = "canceled"
+	entry.UpdatedAt = time.Now()
+	err = service.waitlistRepo.UpdateEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	if wasNotified {
+		return service.notifyNextWaitlisted(entry.PostID, entry.StartDate, entry.EndDate)
+	}
+	return nil
+}
+
+func (service *RentService) GetWaitlist(renterId uint) ([]WaitlistResponse, error) {
+	entries, err := service.waitlistRepo.GetRenterEntries(renterId)
+	if err != nil {
+		return nil, err
+	}
+
+	waitlistResponseList := []WaitlistResponse{}
+	for _, entry := range entries {
+		waitlistResponseList = append(waitlistResponseList, *newWaitlistResponse(&entry))
+	}
+	return waitlistResponseList, nil
+}
+
+// checkWaitlistPriority returns ErrWaitlistPriority when the units held for
+// renters notified about the period leave no room for the request. When the
+// renter is the one holding priority, their entry is returned so it can be
+// marked as booked.
+func (service *RentService) checkWaitlistPriority(renterId, postId uint, inventory, quantity int, startDate, endDate time.Time) (*WaitlistEntry, error) {
+	err := service.ExpireWaitlistPriorities()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := service.waitlistRepo.GetOverlappingEntries(postId, "notified", startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var renterEntry *WaitlistEntry
+	heldUnits := 0
+	for i := range entries {
+		if entries[i].RenterID == renterId {
+			renterEntry = &entries[i]
+		} else {
+			heldUnits += entries[i].Quantity
+		}
+	}
+
+	if heldUnits > 0 {
+		bookedUnits, err := service.bookedUnits(postId, startDate, endDate, 0)
+		if err != nil {
+			return nil, err
+		}
+		if bookedUnits+heldUnits+quantity > inventory {
+			return nil, ErrWaitlistPriority
+		}
+	}
+	return renterEntry, nil
+}
+
+func (service *RentService) markWaitlistBooked(entry *WaitlistEntry) error {
+	if entry == nil {
+		return nil
+	}
+	entry.Status = "booked"
+	entry.UpdatedAt = time.Now()
+	return service.waitlistRepo.UpdateEntry(entry)
+}
+
+// releaseWaitlist is called when a paid period of a post becomes free again.
+// The change freeing it is already saved by then, so failures are logged
+// rather than failing a cancellation that went through.
+func (service *RentService) releaseWaitlist(postId uint, startDate, endDate time.Time) {
+	err := service.notifyNextWaitlisted(postId, startDate, endDate)
+	if err != nil {
+		zap.L().Error("error releasing waitlist", zap.Uint("postId", postId), zap.Error(err))
+	}
+}
+
+// notifyNextWaitlisted gives priority, oldest first, to the waiting entries
+// overlapping the period whose own period can now be booked, counting the
+// units already held for renters notified before them. The next renters are
+// only notified once earlier priorities are used or expire. An entry that
+// fails is logged and skipped so it does not hold back the next ones.
+func (service *RentService) notifyNextWaitlisted(postId uint, startDate, endDate time.Time) error {
+	entries, err := service.waitlistRepo.GetOverlappingEntries(postId, "waiting", startDate, endDate)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	postDetail, err := service.serviceClient.GetPostByID(postId)
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+
+		bookedUnits, err := service.bookedUnits(entry.PostID, entry.StartDate, entry.EndDate, 0)
+		if err != nil {
+			zap.L().Error("error checking waitlist entry availability", zap.Uint("entryId", entry.ID), zap.Error(err))
+			continue
+		}
+
+		heldEntries, err := service.waitlistRepo.GetOverlappingEntries(entry.PostID, "notified", entry.StartDate, entry.EndDate)
+		if err != nil {
+			zap.L().Error("error checking waitlist entry availability", zap.Uint("entryId", entry.ID), zap.Error(err))
+			continue
+		}
+		heldUnits := 0
+		for _, heldEntry := range heldEntries {
+			heldUnits += heldEntry.Quantity
+		}
+
+		if bookedUnits+heldUnits+entry.Quantity > inventory(postDetail) {
+			continue
+		}
+
+		now := time.Now()
+		priorityUntil := now.Add(waitlistPriorityWindow)
+		entry.Status = "notified"
+		entry.NotifiedAt = &now
+		entry.PriorityUntil = &priorityUntil
+		entry.UpdatedAt = now
+		err = service.waitlistRepo.UpdateEntry(entry)
+		if err != nil {
+			zap.L().Error("error updating waitlist entry", zap.Uint("entryId", entry.ID), zap.Error(err))
+			continue
+		}
+
+		err = service.waitlistNotifier.NotifyWaitlistSpot(entry)
+		if err != nil {
+			zap.L().Error("error notifying waitlisted renter", zap.Uint("entryId", entry.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// ExpireWaitlistPriorities ends the priority of notified renters who did not
+// book in time and passes it on to the next renter in line.
+func (service *RentService) ExpireWaitlistPriorities() error {
+	entries, err := service.waitlistRepo.GetExpiredPriorityEntries(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		entry.Status = "expired"
+		entry.UpdatedAt = time.Now()
+		err = service.waitlistRepo.UpdateEntry(entry)
+		if err != nil {
+			return err
+		}
+
+		err = service.notifyNextWaitlisted(entry.PostID, entry.StartDate, entry.EndDate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunWaitlistExpiry expires waitlist priorities periodically so the next
+// renter is notified even when nobody touches the post.
+func (service *RentService) RunWaitlistExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := service.ExpireWaitlistPriorities(); err != nil {
+				zap.L().Error("error expiring waitlist priorities", zap.Error(err))
+			}
+		}
+	}
+}
+
+func newWaitlistResponse(entry *WaitlistEntry) *WaitlistResponse {
+	return &WaitlistResponse{
+		ID:            entry.ID,
+		PostID:        entry.PostID,
+		StartDate:     entry.StartDate,
+		EndDate:       entry.EndDate,
+		Quantity:      entry.Quantity,
+		Status:        entry.Status,
+		PriorityUntil: entry.PriorityUntil,
+	}
+}
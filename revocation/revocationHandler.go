@@ -0,0 +1,127 @@
+package revocation
+
+import (
+	"errors"
+	"net/http"
+	"rental_service/auth"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type RevocationHandler struct {
+	service *RevocationService
+
+	validate *validator.Validate
+}
+
+func NewRevocationHandler(service *RevocationService, validate *validator.Validate) *RevocationHandler {
+	return &RevocationHandler{service: service, validate: validate}
+}
+
+type RevokeSessionsDto struct {
+	Reason string `json:"reason" validate:"required,min=10,max=1000"`
+}
+
+// RevokeTokenDto revokes a single token. ExpiresAt is the exp claim of the
+// token; the revocation is kept until then.
+type RevokeTokenDto struct {
+	TokenID   string    `json:"tokenId" validate:"required,max=255"`
+	UserID    uint      `json:"userId" validate:"required"`
+	ExpiresAt time.Time `json:"expiresAt" validate:"required"`
+	Reason    string    `json:"reason" validate:"required,min=10,max=1000"`
+}
+
+func (handler *RevocationHandler) RevokeUserSessions(c echo.Context) error {
+	var revokeSessions RevokeSessionsDto
+
+	adminId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	userIdStr := c.Param("userId")
+	if userIdStr == "" {
+		zap.L().Error("missed userId")
+		return echo.NewHTTPError(http.StatusBadRequest, "user ID is required")
+	}
+
+	if err := c.Bind(&revokeSessions); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	revokeSessions.Reason = strings.TrimSpace(revokeSessions.Reason)
+	if err := handler.validate.Struct(revokeSessions); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data, a reason of at least 10 characters is required")
+	}
+
+	err := handler.service.RevokeUserSessions(adminId, userIdStr, revokeSessions.Reason)
+	if err != nil {
+		zap.L().Error("error revoking user sessions", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke user sessions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the sessions of the user have been revoked"})
+}
+
+func (handler *RevocationHandler) RevokeToken(c echo.Context) error {
+	var revokeToken RevokeTokenDto
+
+	adminId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err := c.Bind(&revokeToken); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	revokeToken.Reason = strings.TrimSpace(revokeToken.Reason)
+	if err := handler.validate.Struct(revokeToken); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data, a reason of at least 10 characters is required")
+	}
+
+	err := handler.service.RevokeToken(adminId, revokeToken.UserID, revokeToken.TokenID, revokeToken.ExpiresAt, revokeToken.Reason)
+	if err != nil {
+		zap.L().Error("error revoking token", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke token")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the token has been revoked"})
+}
+
+// Logout revokes the token of the request, or every token of the user with
+// ?all=true.
+func (handler *RevocationHandler) Logout(c echo.Context) error {
+	principal, ok := auth.GetPrincipal(c)
+	if !ok {
+		zap.L().Error("failed to get principal from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	var err error
+	if c.QueryParam("all") == "true" {
+		err = handler.service.RevokeUserSessions(principal.UserID, strconv.FormatUint(uint64(principal.UserID), 10), "logout")
+	} else {
+		err = handler.service.RevokeToken(principal.UserID, principal.UserID, principal.TokenID, principal.ExpiresAt, "logout")
+	}
+	if err != nil {
+		if errors.Is(err, ErrNoTokenID) {
+			return echo.NewHTTPError(http.StatusBadRequest, "this token cannot be revoked alone, log out of all sessions instead")
+		}
+		zap.L().Error("error logging out", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to log out")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "you have been logged out"})
+}
@@ -0,0 +1,61 @@
+package revocation
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedToken is an access token revoked by its ID (the jti claim). It is
+// kept until the token expires.
+type RevokedToken struct {
+	TokenID   string `gorm:"primaryKey"`
+	UserID    uint
+	ExpiresAt time.Time
+	RevokedBy uint
+	Reason    string
+	CreatedAt time.Time
+}
+
+// UserRevocation revokes every token of the user issued before
+// RevokedBefore.
+type UserRevocation struct {
+	UserID        uint `gorm:"primaryKey"`
+	RevokedBefore time.Time
+	RevokedBy     uint
+	Reason        string
+	UpdatedAt     time.Time
+}
+
+type RevocationRepository struct {
+	db *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) *RevocationRepository {
+	return &RevocationRepository{db: db}
+}
+
+func (revocationRepo *RevocationRepository) AddRevokedToken(revokedToken *RevokedToken) error {
+	return revocationRepo.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revokedToken).Error
+}
+
+func (revocationRepo *RevocationRepository) UpdateUserRevocation(userRevocation *UserRevocation) error {
+	return revocationRepo.db.Save(&userRevocation).Error
+}
+
+func (revocationRepo *RevocationRepository) GetRevokedTokens(expiresAfter time.Time) ([]RevokedToken, error) {
+	var revokedTokens []RevokedToken
+	err := revocationRepo.db.Where("expires_at > ?", expiresAfter).Find(&revokedTokens).Error
+	return revokedTokens, err
+}
+
+func (revocationRepo *RevocationRepository) GetUserRevocations() ([]UserRevocation, error) {
+	var userRevocations []UserRevocation
+	err := revocationRepo.db.Find(&userRevocations).Error
+	return userRevocations, err
+}
+
+func (revocationRepo *RevocationRepository) DeleteExpiredTokens(before time.Time) error {
+	return revocationRepo.db.Where("expires_at <= ?", before).Delete(&RevokedToken{}).Error
+}
@@ -0,0 +1,151 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrNoTokenID = errors.New("the token has no ID")
+
+// RevocationService keeps the revocations in memory so the auth middleware
+// checks them without a query. Revocations made through another instance are
+// picked up by Run.
+type RevocationService struct {
+	repo *RevocationRepository
+
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[uint]time.Time
+}
+
+func NewRevocationService(repo *RevocationRepository) (*RevocationService, error) {
+	service := &RevocationService{repo: repo}
+	err := service.Refresh()
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// IsRevoked implements auth.TokenRevocations. Tokens issued in the same second
+// as a revocation of their user are revoked too, as are the tokens without an
+// issue date.
+func (service *RevocationService) IsRevoked(userId uint, tokenId string, issuedAt time.Time) bool {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	if tokenId != "" {
+		if _, ok := service.tokens[tokenId]; ok {
+			return true
+		}
+	}
+
+	revokedBefore, ok := service.users[userId]
+	return ok && issuedAt.Unix() <= revokedBefore.Unix()
+}
+
+// RevokeToken revokes a single token until it expires.
+func (service *RevocationService) RevokeToken(revokedBy, userId uint, tokenId string, expiresAt time.Time, reason string) error {
+	if tokenId == "" {
+		return ErrNoTokenID
+	}
+
+	revokedToken := &RevokedToken{
+		TokenID:   tokenId,
+		UserID:    userId,
+		ExpiresAt: expiresAt,
+		RevokedBy: revokedBy,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	err := service.repo.AddRevokedToken(revokedToken)
+	if err != nil {
+		return err
+	}
+
+	service.mu.Lock()
+	service.tokens[tokenId] = expiresAt
+	service.mu.Unlock()
+	zap.L().Info("token revoked", zap.String("tokenId", tokenId), zap.Uint("userId", userId), zap.Uint("revokedBy", revokedBy), zap.String("reason", reason))
+	return nil
+}
+
+// RevokeUserSessions revokes every token issued to the user so far, which
+// logs them out everywhere.
+func (service *RevocationService) RevokeUserSessions(revokedBy uint, userIdStr, reason string) error {
+	userId, err := strconv.ParseUint(userIdStr, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	userRevocation := &UserRevocation{
+		UserID:        uint(userId),
+		RevokedBefore: time.Now(),
+		RevokedBy:     revokedBy,
+		Reason:        reason,
+		UpdatedAt:     time.Now(),
+	}
+	err = service.repo.UpdateUserRevocation(userRevocation)
+	if err != nil {
+		return err
+	}
+
+	service.mu.Lock()
+	service.users[userRevocation.UserID] = userRevocation.RevokedBefore
+	service.mu.Unlock()
+	zap.L().Info("user sessions revoked", zap.Uint("userId", userRevocation.UserID), zap.Uint("revokedBy", revokedBy), zap.String("reason", reason))
+	return nil
+}
+
+// Refresh reloads the revocations from the database.
+func (service *RevocationService) Refresh() error {
+	revokedTokens, err := service.repo.GetRevokedTokens(time.Now())
+	if err != nil {
+		return err
+	}
+	userRevocations, err := service.repo.GetUserRevocations()
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]time.Time, len(revokedTokens))
+	for _, revokedToken := range revokedTokens {
+		tokens[revokedToken.TokenID] = revokedToken.ExpiresAt
+	}
+	users := make(map[uint]time.Time, len(userRevocations))
+	for _, userRevocation := range userRevocations {
+		users[userRevocation.UserID] = userRevocation.RevokedBefore
+	}
+
+	service.mu.Lock()
+	service.tokens = tokens
+	service.users = users
+	service.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the revocations and forgets the expired tokens every
+// interval until the context is done.
+func (service *RevocationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := service.repo.DeleteExpiredTokens(time.Now()); err != nil {
+				zap.L().Error("error deleting expired revoked tokens", zap.Error(err))
+			}
+			if err := service.Refresh(); err != nil {
+				zap.L().Error("error refreshing token revocations", zap.Error(err))
+			}
+		}
+	}
+}
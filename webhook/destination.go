@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenDestination = errors.New("webhook URL must be https on a public host")
+
+// reservedNetworks are not reachable from the internet but are not covered by
+// the net.IP helpers.
+var reservedNetworks = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"), // carrier-grade NAT
+	mustParseCIDR("192.0.0.0/24"),  // IETF protocol assignments
+	mustParseCIDR("198.18.0.0/15"), // benchmarking
+	mustParseCIDR("240.0.0.0/4"),   // reserved
+	mustParseCIDR("64:ff9b::/96"),  // NAT64, may embed a private IPv4
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// isPublicIP is false for the loopback, private, link-local and other
+// addresses partners could use to reach our internal network.
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDestination rejects URLs that are not https or whose host resolves to
+// a non-public address. Deliveries check the address again when connecting,
+// as the DNS records may change after the subscription is created.
+func checkDestination(ctx context.Context, rawURL string) error {
+	destination, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(destination.Scheme, "https") || destination.Hostname() == "" {
+		return ErrForbiddenDestination
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, destination.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %s does not resolve", ErrForbiddenDestination, destination.Hostname())
+	}
+	for _, address := range addresses {
+		if !isPublicIP(address.IP) {
+			return ErrForbiddenDestination
+		}
+	}
+	return nil
+}
+
+// newDeliveryClient connects only to public addresses, without a proxy, and
+// does not follow redirects, which count as failed deliveries.
+func newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return ErrForbiddenDestination
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   deliveryTimeout,
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
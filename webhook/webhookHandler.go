@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type WebhookHandler struct {
+	service *WebhookService
+
+	validate *validator.Validate
+}
+
+func NewWebhookHandler(service *WebhookService, validate *validator.Validate) *WebhookHandler {
+	return &WebhookHandler{service: service, validate: validate}
+}
+
+type SubscriptionDto struct {
+	URL        string   `json:"url" validate:"required,url,startswith=https://"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16,max=255"`
+	EventTypes []string `json:"eventTypes" validate:"required,min=1,dive,oneof=RentRequestCreated RentRequestConfirmed RentRequestPaid RentRequestRejected RentRequestCancelled RentRequestCompleted RentRequestModified"`
+}
+
+func (handler *WebhookHandler) CreateSubscription(c echo.Context) error {
+	var subscription SubscriptionDto
+
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err := c.Bind(&subscription); err != nil {
+		zap.L().Error("error binding request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to bind request")
+	}
+
+	if err := handler.validate.Struct(subscription); err != nil {
+		zap.L().Error("provided data is invalid", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid data")
+	}
+
+	createdSubscription, err := handler.service.CreateSubscription(userId, subscription)
+	if err != nil {
+		if errors.Is(err, ErrForbiddenDestination) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		zap.L().Error("error creating webhook subscription", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create webhook subscription")
+	}
+
+	return c.JSON(http.StatusCreated, createdSubscription)
+}
+
+func (handler *WebhookHandler) GetSubscriptions(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	subscriptions, err := handler.service.GetSubscriptions(userId)
+	if err != nil {
+		zap.L().Error("error getting webhook subscriptions", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch webhook subscriptions")
+	}
+
+	return c.JSON(http.StatusOK, subscriptions)
+}
+
+func (handler *WebhookHandler) DeleteSubscription(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	subscriptionIdStr := c.Param("subscriptionId")
+	if subscriptionIdStr == "" {
+		zap.L().Error("missed subscriptionId")
+		return echo.NewHTTPError(http.StatusBadRequest, "subscription ID is required")
+	}
+
+	err := handler.service.DeleteSubscription(userId, subscriptionIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "webhook subscription not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to delete webhook subscription", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error deleting webhook subscription", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete webhook subscription")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "the webhook subscription has been deleted"})
+}
+
+func (handler *WebhookHandler) GetDeliveries(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	subscriptionIdStr := c.Param("subscriptionId")
+	if subscriptionIdStr == "" {
+		zap.L().Error("missed subscriptionId")
+		return echo.NewHTTPError(http.StatusBadRequest, "subscription ID is required")
+	}
+
+	status := c.QueryParam("status")
+	pageStr := c.QueryParam("page")
+
+	deliveries, err := handler.service.GetDeliveries(userId, subscriptionIdStr, status, pageStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "webhook subscription not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to retrieve webhook deliveries", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error getting webhook deliveries", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to fetch webhook deliveries")
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func (handler *WebhookHandler) ReplayDelivery(c echo.Context) error {
+	userId, ok := c.Get("userId").(uint)
+	if !ok {
+		zap.L().Error("failed to get userId from context")
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	subscriptionIdStr := c.Param("subscriptionId")
+	deliveryIdStr := c.Param("deliveryId")
+	if subscriptionIdStr == "" || deliveryIdStr == "" {
+		zap.L().Error("missed subscriptionId or deliveryId")
+		return echo.NewHTTPError(http.StatusBadRequest, "subscription ID and delivery ID are required")
+	}
+
+	delivery, err := handler.service.ReplayDelivery(userId, subscriptionIdStr, deliveryIdStr)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "webhook delivery not found")
+		} else if errors.Is(err, ErrNotAllowed) {
+			zap.L().Error("not allowed to replay webhook delivery", zap.Error(err))
+			return echo.NewHTTPError(http.StatusForbidden, "forbidden Access")
+		}
+		zap.L().Error("error replaying webhook delivery", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to replay webhook delivery")
+	}
+
+	return c.JSON(http.StatusAccepted, delivery)
+}
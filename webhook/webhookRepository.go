@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookSubscription is a partner URL that receives the rent request events of the
+// given types concerning the user who created it. EventTypes is a comma
+// separated list.
+type WebhookSubscription struct {
+	ID         uint
+	UserID     uint
+	URL        string
+	Secret     string
+	EventTypes string
+	Active     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// WebhookDelivery is one event sent, or to be sent, to one subscription. Failed
+// deliveries are retried at NextAttemptAt until they run out of attempts and
+// are moved to the "dead" state.
+type WebhookDelivery struct {
+	ID             uint
+	SubscriptionID uint
+	EventID        uint
+	EventType      string
+	Payload        json.RawMessage `gorm:"type:jsonb"`
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (webhookRepo *WebhookRepository) AddSubscription(subscription *WebhookSubscription) error {
+	return webhookRepo.db.Create(&subscription).Error
+}
+
+func (webhookRepo *WebhookRepository) UpdateSubscription(subscription *WebhookSubscription) error {
+	return webhookRepo.db.Save(&subscription).Error
+}
+
+func (webhookRepo *WebhookRepository) GetSubscriptionById(subscriptionId uint) (*WebhookSubscription, error) {
+	var subscription WebhookSubscription
+	err := webhookRepo.db.First(&subscription, subscriptionId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (webhookRepo *WebhookRepository) GetUserSubscriptions(userId uint) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+	err := webhookRepo.db.Model(&WebhookSubscription{}).Where("user_id = ? and active = ?", userId, true).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (webhookRepo *WebhookRepository) GetActiveSubscriptions(userIds []uint) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+	err := webhookRepo.db.Model(&WebhookSubscription{}).Where("user_id IN ? and active = ?", userIds, true).Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// AddDeliveries ignores deliveries that already exist for the same
+// subscription and event, since the relay may publish an event twice.
+func (webhookRepo *WebhookRepository) AddDeliveries(deliveries []WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return webhookRepo.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+func (webhookRepo *WebhookRepository) UpdateDelivery(delivery *WebhookDelivery) error {
+	return webhookRepo.db.Save(&delivery).Error
+}
+
+func (webhookRepo *WebhookRepository) GetDeliveryById(deliveryId uint) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := webhookRepo.db.First(&delivery, deliveryId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ClaimDueDeliveries leases the oldest due deliveries for lease by moving
+// their next attempt past it. Other workers skip them meanwhile, so several
+// can run side by side without sending a delivery twice; saving the outcome
+// of the attempt ends the lease.
+func (webhookRepo *WebhookRepository) ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := webhookRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? and next_attempt_at <= ?", []string{"pending", "failed"}, now).
+			Order("id").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		return tx.Model(&WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return deliveries, err
+}
+
+func (webhookRepo *WebhookRepository) GetSubscriptionDeliveries(subscriptionId uint, status string, offset, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	query := webhookRepo.db.Model(&WebhookDelivery{}).Where("subscription_id = ?", subscriptionId)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id desc").Offset(offset).Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,416 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rental_service/events"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	maxDeliveryAttempts = 8
+	retryBaseDelay      = 30 * time.Second
+	retryMaxDelay       = 6 * time.Hour
+	deliveryBatchSize   = 50
+	deliveryPageSize    = 20
+	deliveryTimeout     = 10 * time.Second
+	// deliveryLease covers sending a whole batch to unresponsive partners.
+	deliveryLease = deliveryBatchSize * deliveryTimeout
+)
+
+var ErrRecordNotFound = errors.New("webhook subscription not found")
+var ErrNotAllowed = errors.New("webhook subscription belongs to another user")
+
+type WebhookService struct {
+	repo   *WebhookRepository
+	client *http.Client
+}
+
+func NewWebhookService(repo *WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo, client: newDeliveryClient()}
+}
+
+type SubscriptionResponse struct {
+	ID         uint      `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type DeliveryResponse struct {
+	ID             uint            `json:"id"`
+	EventID        uint            `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code"`
+	LastError      string          `json:"last_error"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// CreateSubscription stores a new subscription. A secret is generated when
+// none is given; it is only returned here, so partners have to keep it.
+func (service *WebhookService) CreateSubscription(userId uint, subscriptionDto SubscriptionDto) (*SubscriptionResponse, error) {
+	err := checkDestination(context.Background(), subscriptionDto.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := subscriptionDto.Secret
+	if secret == "" {
+		randomBytes := make([]byte, 32)
+		_, err := rand.Read(randomBytes)
+		if err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(randomBytes)
+	}
+
+	subscription := &WebhookSubscription{
+		UserID:     userId,
+		URL:        subscriptionDto.URL,
+		Secret:     secret,
+		EventTypes: strings.Join(subscriptionDto.EventTypes, ","),
+		Active:     true,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	err = service.repo.AddSubscription(subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionResponse := newSubscriptionResponse(subscription)
+	subscriptionResponse.Secret = secret
+	return subscriptionResponse, nil
+}
+
+func (service *WebhookService) GetSubscriptions(userId uint) ([]SubscriptionResponse, error) {
+	subscriptions, err := service.repo.GetUserSubscriptions(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionResponseList := []SubscriptionResponse{}
+	for i := range subscriptions {
+		subscriptionResponseList = append(subscriptionResponseList, *newSubscriptionResponse(&subscriptions[i]))
+	}
+	return subscriptionResponseList, nil
+}
+
+// DeleteSubscription deactivates the subscription. Its deliveries are kept so
+// they can still be listed.
+func (service *WebhookService) DeleteSubscription(userId uint, subscriptionIdStr string) error {
+	subscription, err := service.getSubscription(userId, subscriptionIdStr)
+	if err != nil {
+		return err
+	}
+
+	subscription.Active = false
+	subscription.UpdatedAt = time.Now()
+	return service.repo.UpdateSubscription(subscription)
+}
+
+func (service *WebhookService) GetDeliveries(userId uint, subscriptionIdStr, status, pageStr string) ([]DeliveryResponse, error) {
+	subscription, err := service.getSubscription(userId, subscriptionIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	page := 1
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("invalid page number")
+		}
+	}
+
+	deliveries, err := service.repo.GetSubscriptionDeliveries(subscription.ID, status, (page-1)*deliveryPageSize, deliveryPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveryResponseList := []DeliveryResponse{}
+	for i := range deliveries {
+		deliveryResponseList = append(deliveryResponseList, *newDeliveryResponse(&deliveries[i]))
+	}
+	return deliveryResponseList, nil
+}
+
+// ReplayDelivery queues a delivery to be sent again right away with a fresh
+// set of attempts, whatever its current state.
+func (service *WebhookService) ReplayDelivery(userId uint, subscriptionIdStr, deliveryIdStr string) (*DeliveryResponse, error) {
+	subscription, err := service.getSubscription(userId, subscriptionIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if !subscription.Active {
+		return nil, ErrNotAllowed
+	}
+
+	deliveryId, err := strconv.ParseUint(deliveryIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := service.repo.GetDeliveryById(uint(deliveryId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	if delivery.SubscriptionID != subscription.ID {
+		return nil, ErrRecordNotFound
+	}
+
+	delivery.Status = "pending"
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.UpdatedAt = time.Now()
+	err = service.repo.UpdateDelivery(delivery)
+	if err != nil {
+		return nil, err
+	}
+	return newDeliveryResponse(delivery), nil
+}
+
+// Publish implements events.Publisher. It queues a delivery for every active
+// subscription of the renter and the owner of the rent request that asked
+// for the event type; the deliveries are sent by RunDeliveries.
+func (service *WebhookService) Publish(ctx context.Context, event *events.OutboxEvent) error {
+	var parties struct {
+		RenterID uint `json:"renterId"`
+		OwnerID  uint `json:"ownerId"`
+	}
+	err := json.Unmarshal(event.Payload, &parties)
+	if err != nil {
+		return err
+	}
+
+	subscriptions, err := service.repo.GetActiveSubscriptions([]uint{parties.RenterID, parties.OwnerID})
+	if err != nil {
+		return err
+	}
+
+	var deliveries []WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscribedTo(&subscription, event.EventType) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.EventType,
+			Payload:        event.Payload,
+			Status:         "pending",
+			NextAttemptAt:  time.Now(),
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		})
+	}
+	return service.repo.AddDeliveries(deliveries)
+}
+
+// DeliverDue sends the deliveries whose next attempt is due, claimed so the
+// workers of the other replicas do not send them too.
+func (service *WebhookService) DeliverDue(ctx context.Context) error {
+	deliveries, err := service.repo.ClaimDueDeliveries(time.Now(), deliveryBatchSize, deliveryLease)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		subscription, err := service.repo.GetSubscriptionById(delivery.SubscriptionID)
+		if err != nil {
+			return err
+		}
+
+		if !subscription.Active {
+			delivery.Status = "dead"
+			delivery.LastError = "subscription deleted"
+		} else {
+			service.attemptDelivery(ctx, subscription, delivery)
+		}
+
+		delivery.UpdatedAt = time.Now()
+		err = service.repo.UpdateDelivery(delivery)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunDeliveries sends due deliveries periodically until ctx is done.
+func (service *WebhookService) RunDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := service.DeliverDue(ctx); err != nil {
+				zap.L().Error("error delivering webhooks", zap.Error(err))
+			}
+		}
+	}
+}
+
+// attemptDelivery posts the event and records the outcome on the delivery.
+// Failed attempts are retried with an exponential backoff; after
+// maxDeliveryAttempts the delivery is dead and only a replay sends it again.
+func (service *WebhookService) attemptDelivery(ctx context.Context, subscription *WebhookSubscription, delivery *WebhookDelivery) {
+	delivery.Attempts++
+
+	statusCode, err := service.send(ctx, subscription, delivery)
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		now := time.Now()
+		delivery.Status = "delivered"
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxDeliveryAttempts {
+		delivery.Status = "dead"
+		zap.L().Warn("webhook delivery moved to dead letter", zap.Uint("deliveryId", delivery.ID), zap.Error(err))
+		return
+	}
+	delivery.Status = "failed"
+	delivery.NextAttemptAt = time.Now().Add(retryDelay(delivery.Attempts))
+}
+
+func (service *WebhookService) send(ctx context.Context, subscription *WebhookSubscription, delivery *WebhookDelivery) (int, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"id":      delivery.EventID,
+		"type":    delivery.EventType,
+		"payload": delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Subscriptions made before https was required are not delivered.
+	if !strings.HasPrefix(strings.ToLower(subscription.URL), "https://") {
+		return 0, ErrForbiddenDestination
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", subscription.URL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Webhook-Id", strconv.FormatUint(uint64(delivery.ID), 10))
+	request.Header.Set("X-Webhook-Event", delivery.EventType)
+	request.Header.Set("X-Webhook-Signature", Sign(subscription.Secret, timestamp, requestBody))
+
+	response, err := service.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("an error occurred: status code %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// Sign returns the X-Webhook-Signature header of a delivery:
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">". Partners
+// verify it with their secret and should reject old timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+func subscribedTo(subscription *WebhookSubscription, eventType string) bool {
+	for _, subscribedType := range strings.Split(subscription.EventTypes, ",") {
+		if subscribedType == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (service *WebhookService) getSubscription(userId uint, subscriptionIdStr string) (*WebhookSubscription, error) {
+	subscriptionId, err := strconv.ParseUint(subscriptionIdStr, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := service.repo.GetSubscriptionById(uint(subscriptionId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	if subscription.UserID != userId {
+		return nil, ErrNotAllowed
+	}
+	return subscription, nil
+}
+
+func newSubscriptionResponse(subscription *WebhookSubscription) *SubscriptionResponse {
+	return &SubscriptionResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: strings.Split(subscription.EventTypes, ","),
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+func newDeliveryResponse(delivery *WebhookDelivery) *DeliveryResponse {
+	return &DeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}